@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MergeBase returns the best common ancestor of the revisions, or an empty string if they have none.
+// With more than two revisions, the common ancestor of all of them is returned.
+func (r Repo) MergeBase(ctx context.Context, revs ...string) (string, error) {
+	if len(revs) < 2 {
+		return "", fmt.Errorf("merge base needs at least two revisions")
+	}
+	args := []string{"merge-base"}
+	if len(revs) > 2 {
+		args = append(args, "--octopus")
+	}
+	args = append(args, revs...)
+	out, err := r.runCmd(ctx, "git", args...)
+	if err != nil {
+		var gitErr *GitError
+		// merge-base exits with 1 when there is no common ancestor
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 && gitErr.Stderr == "" {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// IsAncestor returns true if the commit a is an ancestor of the commit b, or the same commit
+func (r Repo) IsAncestor(ctx context.Context, a, b string) (bool, error) {
+	_, err := r.runCmd(ctx, "git", "merge-base", "--is-ancestor", a, b)
+	if err != nil {
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 && gitErr.Stderr == "" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// AheadBehind returns the number of commits of a which are not in b, and the number of commits of b which are not in a
+func (r Repo) AheadBehind(ctx context.Context, a, b string) (ahead, behind int, err error) {
+	out, err := r.runCmd(ctx, "git", "rev-list", "--left-right", "--count", a+"..."+b, "--")
+	if err != nil {
+		return 0, 0, err
+	}
+	counts := strings.Fields(out)
+	if len(counts) != 2 {
+		return 0, 0, fmt.Errorf("unable to parse rev-list count %q", out)
+	}
+	if ahead, err = strconv.Atoi(counts[0]); err != nil {
+		return 0, 0, fmt.Errorf("unable to parse rev-list count %q: %w", out, err)
+	}
+	if behind, err = strconv.Atoi(counts[1]); err != nil {
+		return 0, 0, fmt.Errorf("unable to parse rev-list count %q: %w", out, err)
+	}
+	return ahead, behind, nil
+}
+
+// BranchesContaining returns the local branches containing the commit
+func (r Repo) BranchesContaining(ctx context.Context, commit string) ([]string, error) {
+	return r.refsContaining(ctx, commit, "refs/heads")
+}
+
+// TagsContaining returns the tags containing the commit
+func (r Repo) TagsContaining(ctx context.Context, commit string) ([]string, error) {
+	return r.refsContaining(ctx, commit, "refs/tags")
+}
+
+func (r Repo) refsContaining(ctx context.Context, commit, prefix string) ([]string, error) {
+	out, err := r.runCmd(ctx, "git", "for-each-ref", "--format=%(refname:lstrip=2)", "--contains", commit, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	for _, ref := range strings.Split(out, "\n") {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAncestry(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"f": "a\n"})
+	base, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	_, err = r.runCmd(ctx, "git", "tag", "v1.0.0")
+	require.NoError(t, err)
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature 1", map[string]string{"g": "g\n"})
+	commitTestFiles(t, r, "feature 2", map[string]string{"h": "h\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"f": "b\n"})
+
+	mergeBase, err := r.MergeBase(ctx, "master", "feature")
+	require.NoError(t, err)
+	assert.Equal(t, base.LongHash, mergeBase)
+	mergeBase, err = r.MergeBase(ctx, "master", "feature", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, base.LongHash, mergeBase)
+	_, err = r.MergeBase(ctx, "master")
+	assert.Error(t, err)
+
+	isAncestor, err := r.IsAncestor(ctx, "v1.0.0", "feature")
+	require.NoError(t, err)
+	assert.True(t, isAncestor)
+	isAncestor, err = r.IsAncestor(ctx, "feature", "master")
+	require.NoError(t, err)
+	assert.False(t, isAncestor)
+	_, err = r.IsAncestor(ctx, "unknown", "master")
+	assert.Error(t, err)
+
+	ahead, behind, err := r.AheadBehind(ctx, "feature", "master")
+	require.NoError(t, err)
+	assert.Equal(t, 2, ahead)
+	assert.Equal(t, 1, behind)
+
+	branches, err := r.BranchesContaining(ctx, base.LongHash)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature", "master"}, branches)
+	branches, err = r.BranchesContaining(ctx, "feature")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature"}, branches)
+
+	tags, err := r.TagsContaining(ctx, base.LongHash)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+	tags, err = r.TagsContaining(ctx, "master")
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	// unrelated history
+	_, err = r.runCmd(ctx, "git", "checkout", "--orphan", "orphan")
+	require.NoError(t, err)
+	commitTestFiles(t, r, "orphan", map[string]string{"o": "o\n"})
+	mergeBase, err = r.MergeBase(ctx, "orphan", "master")
+	require.NoError(t, err)
+	assert.Empty(t, mergeBase)
+}
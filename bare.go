@@ -39,17 +39,17 @@ func NewBare(ctx context.Context, path string, opts ...Option) (b BareRepo, err
 		return b, err
 	}
 
-	output, _ := b.repo.runCmd(ctx, "git", "rev-parse", "--is-bare-repository")
-	if !strings.Contains(output, "true") {
-		return b, errors.New("path is not a bare repository")
-	}
-
 	for _, f := range opts {
 		if err := f(ctx, &b.repo); err != nil {
 			return b, err
 		}
 	}
 
+	output, _ := b.repo.runCmd(ctx, "git", "rev-parse", "--is-bare-repository")
+	if !strings.Contains(output, "true") {
+		return b, errors.New("path is not a bare repository")
+	}
+
 	return b, nil
 }
 
@@ -134,10 +134,22 @@ func (b BareRepo) Path() string {
 	return b.repo.path
 }
 
+func (b BareRepo) Commits(ctx context.Context, from, to string) ([]Commit, error) {
+	return b.repo.Commits(ctx, from, to)
+}
+
+func (b BareRepo) CommitsIter(ctx context.Context, from, to string) *CommitIter {
+	return b.repo.CommitsIter(ctx, from, to)
+}
+
 func (b BareRepo) CommitsBetween(ctx context.Context, from, to time.Time, branch string) ([]Commit, error) {
 	return b.repo.CommitsBetween(ctx, from, to, branch)
 }
 
+func (b BareRepo) CommitsBetweenIter(ctx context.Context, from, to time.Time, branch string) *CommitIter {
+	return b.repo.CommitsBetweenIter(ctx, from, to, branch)
+}
+
 func (b BareRepo) DefaultBranch(ctx context.Context) (string, error) {
 	return b.repo.DefaultBranch(ctx)
 }
@@ -145,3 +157,71 @@ func (b BareRepo) DefaultBranch(ctx context.Context) (string, error) {
 func (b BareRepo) Tags(ctx context.Context) ([]Tag, error) {
 	return b.repo.Tags(ctx)
 }
+
+func (b BareRepo) TagsIter(ctx context.Context) *TagIter {
+	return b.repo.TagsIter(ctx)
+}
+
+func (b BareRepo) Log(ctx context.Context, opts LogOpts) ([]Commit, error) {
+	return b.repo.Log(ctx, opts)
+}
+
+func (b BareRepo) LogIter(ctx context.Context, opts LogOpts) *CommitIter {
+	return b.repo.LogIter(ctx, opts)
+}
+
+func (b BareRepo) Changelog(ctx context.Context, from, to string, opts ChangelogOpts) (*Changelog, error) {
+	return b.repo.Changelog(ctx, from, to, opts)
+}
+
+func (b BareRepo) GrepDiff(ctx context.Context, rangeSpec string, regexp *regexp.Regexp) ([]DiffMatch, error) {
+	return b.repo.GrepDiff(ctx, rangeSpec, regexp)
+}
+
+func (b BareRepo) DiffRefs(ctx context.Context, from, to string, opts DiffOpts) (map[string]File, error) {
+	return b.repo.DiffRefs(ctx, from, to, opts)
+}
+
+func (b BareRepo) FormatPatch(ctx context.Context, rangeSpec string) ([]Patch, error) {
+	return b.repo.FormatPatch(ctx, rangeSpec)
+}
+
+func (b BareRepo) Blame(ctx context.Context, rev, path string, opts BlameOpts) ([]BlameLine, error) {
+	return b.repo.Blame(ctx, rev, path, opts)
+}
+
+func (b BareRepo) FileHistory(ctx context.Context, path string, opts FileHistoryOpts) ([]FileRevision, error) {
+	return b.repo.FileHistory(ctx, path, opts)
+}
+
+func (b BareRepo) FileHistoryIter(ctx context.Context, path string, opts FileHistoryOpts) *FileHistoryIter {
+	return b.repo.FileHistoryIter(ctx, path, opts)
+}
+
+func (b BareRepo) MergeBase(ctx context.Context, revs ...string) (string, error) {
+	return b.repo.MergeBase(ctx, revs...)
+}
+
+func (b BareRepo) IsAncestor(ctx context.Context, a, c string) (bool, error) {
+	return b.repo.IsAncestor(ctx, a, c)
+}
+
+func (b BareRepo) AheadBehind(ctx context.Context, a, c string) (ahead, behind int, err error) {
+	return b.repo.AheadBehind(ctx, a, c)
+}
+
+func (b BareRepo) BranchesContaining(ctx context.Context, commit string) ([]string, error) {
+	return b.repo.BranchesContaining(ctx, commit)
+}
+
+func (b BareRepo) TagsContaining(ctx context.Context, commit string) ([]string, error) {
+	return b.repo.TagsContaining(ctx, commit)
+}
+
+func (b BareRepo) Branches(ctx context.Context, opts BranchListOpts) ([]Branch, error) {
+	return b.repo.Branches(ctx, opts)
+}
+
+func (b BareRepo) CanMerge(ctx context.Context, base, head string) (MergeCheck, error) {
+	return b.repo.CanMerge(ctx, base, head)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 
@@ -40,3 +41,28 @@ func TestBare(t *testing.T) {
 	t.Logf("%s", string(readmeContent))
 
 }
+
+func TestBareCommitsIter(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	commitTestFiles(t, r, "b", map[string]string{"b": "b\n"})
+	path := t.TempDir()
+	out, err := exec.Command("git", "clone", "--quiet", "--bare", r.path, path).CombinedOutput()
+	require.NoError(t, err, string(out))
+	bare, err := NewBare(ctx, path)
+	require.NoError(t, err)
+
+	it := bare.CommitsIter(ctx, "", "HEAD")
+	defer it.Close()
+	var hashes []string
+	for it.Next() {
+		hashes = append(hashes, it.Commit().LongHash)
+	}
+	require.NoError(t, it.Err())
+	assert.Len(t, hashes, 2)
+
+	commits, err := bare.Commits(ctx, "", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Equal(t, hashes[0], commits[0].LongHash)
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BlameOpts struct {
+	// StartLine and EndLine restrict the blame to a range of lines, from 1. Zero values are the first and the last line.
+	StartLine int
+	EndLine   int
+	// IgnoreWhitespace ignores the whitespace changes (-w)
+	IgnoreWhitespace bool
+	// DetectMoves detects the lines moved within the file (-M)
+	DetectMoves bool
+	// DetectCopies detects the lines moved or copied from the other files modified in the same commit (-C)
+	DetectCopies bool
+	// IgnoreRevsFile is the path of a file listing the revisions to ignore, as git blame --ignore-revs-file
+	IgnoreRevsFile string
+	// IgnoreRevs are revisions to ignore
+	IgnoreRevs []string
+}
+
+// BlameLine is a line of a file with the commit which introduced it
+type BlameLine struct {
+	// LineNo is the line number in the blamed file
+	LineNo int
+	// OrigLineNo and OrigPath are the line number and the path of the line in the commit
+	OrigLineNo int
+	OrigPath   string
+	Content    string
+	// Commit describes the commit which introduced the line, its files are not computed.
+	// The hash is made of zeros if the line is not committed yet.
+	Commit Commit
+	// PreviousHash and PreviousPath are the parent commit and the path of the file before the commit, if any
+	PreviousHash string
+	PreviousPath string
+	// Boundary is set when the commit is the boundary of the blame, the line may be older
+	Boundary bool
+}
+
+// Blame returns the lines of the file at the revision, or in the working tree if rev is empty, with the commit which last changed them
+func (r Repo) Blame(ctx context.Context, rev, path string, opts BlameOpts) ([]BlameLine, error) {
+	args := []string{"blame", "--line-porcelain"}
+	if opts.StartLine > 0 || opts.EndLine > 0 {
+		start, end := "1", ""
+		if opts.StartLine > 0 {
+			start = strconv.Itoa(opts.StartLine)
+		}
+		if opts.EndLine > 0 {
+			end = strconv.Itoa(opts.EndLine)
+		}
+		args = append(args, "-L", start+","+end)
+	}
+	if opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	if opts.DetectMoves {
+		args = append(args, "-M")
+	}
+	if opts.DetectCopies {
+		args = append(args, "-C")
+	}
+	if opts.IgnoreRevsFile != "" {
+		args = append(args, "--ignore-revs-file", opts.IgnoreRevsFile)
+	}
+	for _, rev := range opts.IgnoreRevs {
+		args = append(args, "--ignore-rev", rev)
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(args, "--", path)
+
+	// the output is not read with runCmd which rewrites the CR of the lines content
+	out := new(bytes.Buffer)
+	if err := r.execCmd(ctx, Command{Name: "git", Args: args, Stdout: out}); err != nil {
+		return nil, err
+	}
+	return parseBlame(out.String())
+}
+
+// parseBlame parses the git blame --line-porcelain output
+func parseBlame(out string) ([]BlameLine, error) {
+	var lines []BlameLine
+	var current *BlameLine
+	for _, l := range strings.Split(out, "\n") {
+		if current == nil {
+			if l == "" {
+				continue
+			}
+			// <hash> <orig line> <final line> [<lines in group>]
+			fields := strings.Fields(l)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("unable to parse blame header %q", l)
+			}
+			current = &BlameLine{}
+			current.Commit.LongHash = fields[0]
+			if len(fields[0]) >= 7 {
+				current.Commit.Hash = fields[0][:7]
+			}
+			var err error
+			if current.OrigLineNo, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, fmt.Errorf("unable to parse blame header %q: %w", l, err)
+			}
+			if current.LineNo, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("unable to parse blame header %q: %w", l, err)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(l, "\t") {
+			current.Content = strings.TrimSuffix(l[1:], "\r")
+			lines = append(lines, *current)
+			current = nil
+			continue
+		}
+
+		key, value, _ := strings.Cut(l, " ")
+		switch key {
+		case "author":
+			current.Commit.Author = value
+		case "author-mail":
+			current.Commit.AuthorEmail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "author-time":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse blame author time %q: %w", value, err)
+			}
+			current.Commit.Date = time.Unix(ts, 0)
+		case "committer":
+			current.Commit.Committer = value
+		case "committer-mail":
+			current.Commit.CommitterEmail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "committer-time":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse blame committer time %q: %w", value, err)
+			}
+			current.Commit.CommitterDate = time.Unix(ts, 0)
+		case "summary":
+			current.Commit.Subject = value
+		case "previous":
+			current.PreviousHash, current.PreviousPath, _ = strings.Cut(value, " ")
+			current.PreviousPath = unquotePath(current.PreviousPath)
+		case "filename":
+			current.OrigPath = unquotePath(value)
+		case "boundary":
+			current.Boundary = true
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unable to parse blame: unterminated line %d", current.LineNo)
+	}
+	return lines, nil
+}
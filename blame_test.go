@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseBlame(t *testing.T) {
+	out := "4a9922c6da12d70b58a7e782bb3d81650601600c 1 1 2\n" +
+		"author foo.bar\n" +
+		"author-mail <foo@bar.com>\n" +
+		"author-time 1685613600\n" +
+		"author-tz +0200\n" +
+		"committer bar.foo\n" +
+		"committer-mail <bar@foo.com>\n" +
+		"committer-time 1685617200\n" +
+		"committer-tz +0200\n" +
+		"summary second\n" +
+		"previous 821aeaa48e33373a1b61644b31a7b240092262a4 \"old\\tname.go\"\n" +
+		"filename new.go\n" +
+		"\tpackage repo\n" +
+		"821aeaa48e33373a1b61644b31a7b240092262a4 3 2\n" +
+		"author foo.bar\n" +
+		"author-mail <foo@bar.com>\n" +
+		"author-time 1685610000\n" +
+		"author-tz +0200\n" +
+		"committer foo.bar\n" +
+		"committer-mail <foo@bar.com>\n" +
+		"committer-time 1685610000\n" +
+		"committer-tz +0200\n" +
+		"summary first\n" +
+		"boundary\n" +
+		"filename old\tname.go\n" +
+		"\t\r\n"
+
+	lines, err := parseBlame(out)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, 1, lines[0].LineNo)
+	assert.Equal(t, 1, lines[0].OrigLineNo)
+	assert.Equal(t, "new.go", lines[0].OrigPath)
+	assert.Equal(t, "package repo", lines[0].Content)
+	assert.Equal(t, "4a9922c", lines[0].Commit.Hash)
+	assert.Equal(t, "foo@bar.com", lines[0].Commit.AuthorEmail)
+	assert.Equal(t, int64(1685613600), lines[0].Commit.Date.Unix())
+	assert.Equal(t, "bar.foo", lines[0].Commit.Committer)
+	assert.Equal(t, "second", lines[0].Commit.Subject)
+	assert.Equal(t, "821aeaa48e33373a1b61644b31a7b240092262a4", lines[0].PreviousHash)
+	assert.Equal(t, "old\tname.go", lines[0].PreviousPath)
+	assert.False(t, lines[0].Boundary)
+	assert.Equal(t, 2, lines[1].LineNo)
+	assert.Equal(t, 3, lines[1].OrigLineNo)
+	assert.Equal(t, "", lines[1].Content)
+	assert.True(t, lines[1].Boundary)
+
+	_, err = parseBlame("4a9922c6da12d70b58a7e782bb3d81650601600c 1 1 2\nauthor foo.bar\n")
+	assert.Error(t, err)
+}
+
+func TestBlame(t *testing.T) {
+	r := initTestRepo(t, map[string]string{"f": "a\nb\nc\n"})
+	first, err := r.LatestCommit(context.TODO(), CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	commitTestFiles(t, r, "second", map[string]string{"f": "a\nB\nc\n"})
+	second, err := r.LatestCommit(context.TODO(), CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+
+	lines, err := r.Blame(context.TODO(), "HEAD", "f", BlameOpts{})
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, first.LongHash, lines[0].Commit.LongHash)
+	assert.Equal(t, second.LongHash, lines[1].Commit.LongHash)
+	assert.Equal(t, "B", lines[1].Content)
+	assert.Equal(t, first.LongHash, lines[1].PreviousHash)
+
+	lines, err = r.Blame(context.TODO(), "", "f", BlameOpts{StartLine: 2, EndLine: 2, IgnoreRevs: []string{second.LongHash}})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, 2, lines[0].LineNo)
+	assert.Equal(t, first.LongHash, lines[0].Commit.LongHash)
+}
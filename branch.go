@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BranchListOpts struct {
+	// Remote lists the remote-tracking branches along with the local branches
+	Remote bool
+	// Merged lists only the branches whose tip is reachable from this ref
+	Merged string
+	// NoMerged lists only the branches whose tip is not reachable from this ref
+	NoMerged string
+}
+
+type Branch struct {
+	// Name is the short name of the branch (ie. master, or origin/master for a remote-tracking branch)
+	Name string
+	// RefName is the full name of the branch (ie. refs/heads/master)
+	RefName string
+	Remote  bool
+	// Current is set for the checked out branch
+	Current bool
+	// Hash is the tip commit of the branch
+	Hash string
+	// CommitDate is the committer date of the tip commit
+	CommitDate time.Time
+	Subject    string
+	// Upstream is the upstream branch of a local branch (ie. origin/master), if any
+	Upstream string
+	// UpstreamGone is set when the upstream branch does not exist anymore
+	UpstreamGone bool
+	// Ahead and Behind are the number of commits of the branch which are not in its upstream, and the other way around
+	Ahead  int
+	Behind int
+}
+
+const branchFormat = "%(refname)%00%(objectname)%00%(HEAD)%00%(symref)%00%(committerdate:unix)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(contents:subject)"
+
+// Branches returns the local branches, and the remote-tracking branches if opts.Remote is set, sorted by name
+func (r Repo) Branches(ctx context.Context, opts BranchListOpts) ([]Branch, error) {
+	args := []string{"for-each-ref", "--format=" + branchFormat}
+	if opts.Merged != "" {
+		args = append(args, "--merged="+opts.Merged)
+	}
+	if opts.NoMerged != "" {
+		args = append(args, "--no-merged="+opts.NoMerged)
+	}
+	args = append(args, "refs/heads")
+	if opts.Remote {
+		args = append(args, "refs/remotes")
+	}
+	out, err := r.runCmd(ctx, "git", args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list branches: %w", err)
+	}
+	return parseBranches(out)
+}
+
+// parseBranches parses the git for-each-ref output formatted with branchFormat
+func parseBranches(out string) ([]Branch, error) {
+	var branches []Branch
+	for _, l := range strings.Split(out, "\n") {
+		if l == "" {
+			continue
+		}
+		fields := strings.SplitN(l, "\x00", 8)
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("unable to parse branch %q", l)
+		}
+		if fields[3] != "" {
+			// symbolic ref, ie. refs/remotes/origin/HEAD
+			continue
+		}
+		b := Branch{
+			RefName:  fields[0],
+			Hash:     fields[1],
+			Current:  fields[2] == "*",
+			Upstream: fields[5],
+			Subject:  fields[7],
+		}
+		if name, ok := strings.CutPrefix(b.RefName, "refs/heads/"); ok {
+			b.Name = name
+		} else {
+			b.Name = strings.TrimPrefix(b.RefName, "refs/remotes/")
+			b.Remote = true
+		}
+		if fields[4] != "" {
+			ts, err := strconv.ParseInt(fields[4], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse branch %q: %w", l, err)
+			}
+			b.CommitDate = time.Unix(ts, 0)
+		}
+		// [ahead N][, ][behind M] or gone
+		for _, track := range strings.Split(fields[6], ", ") {
+			kind, count, _ := strings.Cut(track, " ")
+			switch kind {
+			case "gone":
+				b.UpstreamGone = true
+			case "ahead":
+				b.Ahead, _ = strconv.Atoi(count)
+			case "behind":
+				b.Behind, _ = strconv.Atoi(count)
+			}
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranches(t *testing.T) {
+	ctx := context.TODO()
+	origin := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, origin.CheckoutNewBranch(ctx, "gone"))
+	require.NoError(t, origin.Checkout(ctx, "master"))
+
+	path := t.TempDir()
+	out, err := exec.Command("git", "clone", "--quiet", origin.path, path).CombinedOutput()
+	require.NoError(t, err, string(out))
+	r, err := New(ctx, path)
+	require.NoError(t, err)
+	_, err = r.runCmd(ctx, "git", "checkout", "--quiet", "gone")
+	require.NoError(t, err)
+	require.NoError(t, r.Checkout(ctx, "master"))
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature", map[string]string{"b": "b\n"})
+	require.NoError(t, r.SetUpstream(ctx, "feature", "origin/master"))
+	require.NoError(t, r.Checkout(ctx, "master"))
+	require.NoError(t, r.CheckoutNewBranch(ctx, "merged"))
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "local", map[string]string{"c": "c\n"})
+
+	require.NoError(t, origin.DeleteBranch(ctx, "gone"))
+	_, err = r.runCmd(ctx, "git", "fetch", "--prune", "--quiet")
+	require.NoError(t, err)
+
+	branches, err := r.Branches(ctx, BranchListOpts{})
+	require.NoError(t, err)
+	require.Len(t, branches, 4)
+	assert.Equal(t, "feature", branches[0].Name)
+	assert.Equal(t, "refs/heads/feature", branches[0].RefName)
+	assert.Equal(t, "origin/master", branches[0].Upstream)
+	assert.Equal(t, 1, branches[0].Ahead)
+	assert.Equal(t, 0, branches[0].Behind)
+	assert.Contains(t, branches[0].Subject, "feature")
+	assert.False(t, branches[0].CommitDate.IsZero())
+	assert.Equal(t, "gone", branches[1].Name)
+	assert.True(t, branches[1].UpstreamGone)
+	assert.Equal(t, "master", branches[2].Name)
+	assert.True(t, branches[2].Current)
+	assert.Equal(t, 1, branches[2].Ahead)
+	assert.Equal(t, "merged", branches[3].Name)
+	assert.Empty(t, branches[3].Upstream)
+
+	branches, err = r.Branches(ctx, BranchListOpts{Remote: true})
+	require.NoError(t, err)
+	require.Len(t, branches, 5)
+	assert.Equal(t, "origin/master", branches[4].Name)
+	assert.True(t, branches[4].Remote)
+
+	branches, err = r.Branches(ctx, BranchListOpts{Merged: "master"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone", "master", "merged"}, branchNames(branches))
+	branches, err = r.Branches(ctx, BranchListOpts{NoMerged: "master"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature"}, branchNames(branches))
+
+	require.NoError(t, r.RenameBranch(ctx, "merged", "renamed"))
+	require.Error(t, r.DeleteBranch(ctx, "feature"))
+	require.NoError(t, r.ForceDeleteBranch(ctx, "feature"))
+	require.NoError(t, r.SetUpstream(ctx, "master", ""))
+	branches, err = r.Branches(ctx, BranchListOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone", "master", "renamed"}, branchNames(branches))
+	assert.Empty(t, branches[1].Upstream)
+}
+
+func branchNames(branches []Branch) []string {
+	var names []string
+	for _, b := range branches {
+		names = append(names, b.Name)
+	}
+	return names
+}
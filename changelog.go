@@ -0,0 +1,340 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ChangelogType is a section of a changelog
+type ChangelogType struct {
+	Type  string
+	Title string
+}
+
+// DefaultChangelogTypes are the conventional commit types listed in a changelog, in order
+var DefaultChangelogTypes = []ChangelogType{
+	{Type: "feat", Title: "Features"},
+	{Type: "fix", Title: "Bug Fixes"},
+	{Type: "perf", Title: "Performance Improvements"},
+	{Type: "revert", Title: "Reverts"},
+}
+
+// ChangelogOtherType is the type of the section grouping the other commits when ChangelogOpts.All is set
+const ChangelogOtherType = "other"
+
+// ChangelogOpts is a optional struct for Changelog
+type ChangelogOpts struct {
+	// Title of the changelog. Default to the "to" revision
+	Title string
+	// Types are the listed conventional commit types, in order. Default to DefaultChangelogTypes
+	Types []ChangelogType
+	// All lists the commits whose type is not in Types, and the non conventional commits, in an "Other Changes" section
+	All bool
+	// Match are the glob patterns of the release tags used when "from" is empty. Default to v[0-9]*
+	Match []string
+	// CommitURL is the format of the commit links, the long hash being the only argument (ie. https://github.com/fsamin/go-repo/commit/%s).
+	// Default to the commit URL of the origin remote, if it is hosted on a known forge.
+	CommitURL string
+}
+
+// Changelog is the list of the changes between two revisions, grouped by section and scope
+type Changelog struct {
+	Title           string                    `json:"title"`
+	From            string                    `json:"from"`
+	To              string                    `json:"to"`
+	Date            time.Time                 `json:"date"`
+	Sections        []ChangelogSection        `json:"sections"`
+	BreakingChanges []ChangelogBreakingChange `json:"breaking_changes"`
+	Contributors    []Identity                `json:"contributors"`
+}
+
+// ChangelogSection groups the entries of a conventional commit type
+type ChangelogSection struct {
+	Type   string           `json:"type"`
+	Title  string           `json:"title"`
+	Scopes []ChangelogScope `json:"scopes"`
+}
+
+// ChangelogScope groups the entries of a section with the same scope, the scope being empty for the unscoped commits
+type ChangelogScope struct {
+	Scope   string           `json:"scope"`
+	Entries []ChangelogEntry `json:"entries"`
+}
+
+// ChangelogEntry is a commit of a changelog
+type ChangelogEntry struct {
+	Hash        string    `json:"hash"`
+	LongHash    string    `json:"long_hash"`
+	URL         string    `json:"url,omitempty"`
+	Type        string    `json:"type"`
+	Scope       string    `json:"scope"`
+	Description string    `json:"description"`
+	Breaking    bool      `json:"breaking"`
+	Author      Identity  `json:"author"`
+	Date        time.Time `json:"date"`
+	Commit      Commit    `json:"-"`
+}
+
+// ChangelogBreakingChange is a breaking change of a changelog, from a "!" commit type or a BREAKING CHANGE footer
+type ChangelogBreakingChange struct {
+	Hash        string `json:"hash"`
+	URL         string `json:"url,omitempty"`
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// Changelog groups the conventional commits between two revisions by type and scope.
+// If from is empty, the latest tag matching opts.Match before to is used, or the whole history if there is none.
+// If to is empty, HEAD is used.
+func (r Repo) Changelog(ctx context.Context, from, to string, opts ChangelogOpts) (*Changelog, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = DefaultChangelogTypes
+	}
+	if len(opts.Match) == 0 {
+		opts.Match = []string{"v[0-9]*"}
+	}
+	if opts.Title == "" {
+		opts.Title = to
+	}
+	if opts.CommitURL == "" {
+		if fetchURL, err := r.FetchURL(ctx); err == nil {
+			opts.CommitURL = commitURLFormat(fetchURL)
+		}
+	}
+
+	if from == "" {
+		var err error
+		from, err = r.previousTag(ctx, to, opts.Match)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	last, err := r.GetCommit(ctx, to, CommitOption{DisableDiffDetail: true, DisableFiles: true})
+	if err != nil {
+		return nil, err
+	}
+	cl := &Changelog{Title: opts.Title, From: from, To: to, Date: last.Date}
+
+	logOpts := LogOpts{
+		Revisions:    []string{to},
+		NoMerges:     true,
+		CommitOption: CommitOption{DisableDiffDetail: true, DisableFiles: true},
+	}
+	if from != "" {
+		logOpts.Revisions = []string{from + ".." + to}
+	}
+
+	sections := make(map[string]*ChangelogSection)
+	for _, t := range opts.Types {
+		sections[t.Type] = &ChangelogSection{Type: t.Type, Title: t.Title}
+	}
+	other := &ChangelogSection{Type: ChangelogOtherType, Title: "Other Changes"}
+	contributors := make(map[string]Identity)
+
+	it := r.LogIter(ctx, logOpts)
+	defer it.Close()
+	for it.Next() {
+		c := it.Commit()
+		for _, id := range append([]Identity{{Name: c.Author, Email: c.AuthorEmail}}, c.CoAuthors()...) {
+			key := strings.ToLower(id.Email)
+			if key == "" {
+				key = id.Name
+			}
+			if _, has := contributors[key]; !has {
+				contributors[key] = id
+			}
+		}
+
+		e := ChangelogEntry{
+			Hash:        c.Hash,
+			LongHash:    c.LongHash,
+			Description: c.Subject,
+			Author:      Identity{Name: c.Author, Email: c.AuthorEmail},
+			Date:        c.Date,
+			Commit:      c,
+		}
+		if opts.CommitURL != "" {
+			e.URL = fmt.Sprintf(opts.CommitURL, c.LongHash)
+		}
+
+		cc, err := c.Conventional()
+		if err == nil {
+			e.Type, e.Scope, e.Description, e.Breaking = cc.Type, cc.Scope, cc.Description, cc.Breaking
+			for _, b := range cc.BreakingChanges {
+				cl.BreakingChanges = append(cl.BreakingChanges, ChangelogBreakingChange{Hash: c.Hash, URL: e.URL, Scope: cc.Scope, Description: b})
+			}
+		}
+
+		if s, has := sections[e.Type]; has {
+			s.add(e)
+		} else if opts.All {
+			other.add(e)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range opts.Types {
+		if s := sections[t.Type]; len(s.Scopes) > 0 {
+			cl.Sections = append(cl.Sections, *s)
+		}
+	}
+	if len(other.Scopes) > 0 {
+		cl.Sections = append(cl.Sections, *other)
+	}
+	for i := range cl.Sections {
+		sort.SliceStable(cl.Sections[i].Scopes, func(a, b int) bool {
+			return cl.Sections[i].Scopes[a].Scope < cl.Sections[i].Scopes[b].Scope
+		})
+	}
+
+	for _, id := range contributors {
+		cl.Contributors = append(cl.Contributors, id)
+	}
+	sort.Slice(cl.Contributors, func(i, j int) bool {
+		if cl.Contributors[i].Name != cl.Contributors[j].Name {
+			return cl.Contributors[i].Name < cl.Contributors[j].Name
+		}
+		return cl.Contributors[i].Email < cl.Contributors[j].Email
+	})
+
+	return cl, nil
+}
+
+func (s *ChangelogSection) add(e ChangelogEntry) {
+	for i := range s.Scopes {
+		if s.Scopes[i].Scope == e.Scope {
+			s.Scopes[i].Entries = append(s.Scopes[i].Entries, e)
+			return
+		}
+	}
+	s.Scopes = append(s.Scopes, ChangelogScope{Scope: e.Scope, Entries: []ChangelogEntry{e}})
+}
+
+// previousTag returns the latest tag matching the patterns reachable from rev, excluding the tags on rev itself
+func (r Repo) previousTag(ctx context.Context, rev string, match []string) (string, error) {
+	tag, err := r.latestTag(ctx, rev, match)
+	if err != nil || tag == "" {
+		return tag, err
+	}
+	tagCommit, err := r.runCmd(ctx, "git", "rev-parse", "--verify", tag+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	revCommit, err := r.runCmd(ctx, "git", "rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	if tagCommit != revCommit {
+		return tag, nil
+	}
+	// rev is tagged, look for the previous tag from its first parent
+	if _, err := r.runCmd(ctx, "git", "rev-parse", "--verify", "--quiet", rev+"~1"); err != nil {
+		return "", nil
+	}
+	return r.latestTag(ctx, rev+"~1", match)
+}
+
+// commitURLFormat returns the format of the commit links of a remote hosted on GitHub, GitLab, Bitbucket or Gitea
+func commitURLFormat(fetchURL string) string {
+	fetchURL = strings.TrimSuffix(strings.TrimSuffix(fetchURL, "/"), ".git")
+	var host, path string
+	if u, err := url.Parse(fetchURL); err == nil && u.Host != "" {
+		host, path = u.Hostname(), strings.TrimPrefix(u.Path, "/")
+	} else if i := strings.Index(fetchURL, ":"); i > 0 {
+		// [user@]server:project.git
+		host, path = fetchURL[:i], fetchURL[i+1:]
+		if j := strings.LastIndex(host, "@"); j >= 0 {
+			host = host[j+1:]
+		}
+	}
+	if host == "" || path == "" {
+		return ""
+	}
+	webURL := "https://" + host + "/" + path
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return webURL + "/-/commit/%s"
+	case strings.Contains(host, "bitbucket"):
+		return webURL + "/commits/%s"
+	case strings.Contains(host, "github"), strings.Contains(host, "gitea"), strings.Contains(host, "codeberg"):
+		return webURL + "/commit/%s"
+	}
+	return ""
+}
+
+// ChangelogFormat is a rendering format of a changelog
+type ChangelogFormat string
+
+const (
+	ChangelogMarkdown ChangelogFormat = "markdown"
+	ChangelogJSON     ChangelogFormat = "json"
+)
+
+// Render writes the changelog in the given format
+func (c Changelog) Render(w io.Writer, format ChangelogFormat) error {
+	switch format {
+	case ChangelogMarkdown, "":
+		return changelogMarkdownTemplate.Execute(w, c)
+	case ChangelogJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+	return fmt.Errorf("unsupported changelog format %q", format)
+}
+
+// RenderTemplate writes the changelog with a text/template. The template is executed with the Changelog as data
+// and the "link" function rendering a markdown link to a commit.
+func (c Changelog) RenderTemplate(w io.Writer, text string) error {
+	tmpl, err := template.New("changelog").Funcs(changelogFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid changelog template: %w", err)
+	}
+	return tmpl.Execute(w, c)
+}
+
+var changelogFuncs = template.FuncMap{
+	"link": func(hash, url string) string {
+		if url == "" {
+			return hash
+		}
+		return "[" + hash + "](" + url + ")"
+	},
+}
+
+var changelogMarkdownTemplate = template.Must(template.New("markdown").Funcs(changelogFuncs).Parse(`## {{ .Title }} ({{ .Date.Format "2006-01-02" }})
+{{- if .BreakingChanges }}
+
+### BREAKING CHANGES
+{{ range .BreakingChanges }}
+* {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Description }} ({{ link .Hash .URL }})
+{{- end }}
+{{- end }}
+{{- range .Sections }}
+
+### {{ .Title }}
+{{ range .Scopes }}{{ $scope := .Scope }}{{ range .Entries }}
+* {{ if $scope }}**{{ $scope }}:** {{ end }}{{ .Description }} ({{ link .Hash .URL }})
+{{- end }}{{ end }}
+{{- end }}
+{{- if .Contributors }}
+
+### Contributors
+{{ range .Contributors }}
+* {{ .Name }}{{ if .Email }} <{{ .Email }}>{{ end }}
+{{- end }}
+{{- end }}
+`))
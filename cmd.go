@@ -3,53 +3,78 @@ package repo
 import (
 	"bytes"
 	"context"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
-func (r Repo) runCmd(ctx context.Context, name string, args ...string) (stdOut string, err error) {
-	cmd := exec.CommandContext(ctx, name, args...)
-	buffOut := new(bytes.Buffer)
-	buffErr := new(bytes.Buffer)
-	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
-	cmd.Dir = r.path
-	cmd.Stderr = buffErr
-	cmd.Stdout = buffOut
+// Command describes a command to be run by an Executor
+type Command struct {
+	// Dir is the working directory of the command
+	Dir string
+	// Name is the program to run, usually "git"
+	Name string
+	Args []string
+	// Env holds the environment variables computed by the repo (ie. GIT_SSH), on top of the default ones
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
 
-	if r.sshKey != nil {
-		envs, err := r.setupSSHKey()
-		if err != nil {
-			return "", err
-		}
-		cmd.Env = append(cmd.Env, envs...)
-		if r.verbose {
-			r.log("Using %v\n", envs)
-		}
-	}
+// Executor runs the commands issued by a Repo. Implement it to record, replay, sandbox or remote the git commands
+type Executor interface {
+	Run(ctx context.Context, cmd Command) error
+}
 
-	if r.verbose {
-		r.log("Running command %+v\n", cmd)
-	}
+// ExecExecutor is the default Executor, it runs commands on the local host with os/exec
+type ExecExecutor struct{}
 
+// Run runs the command with os/exec. The process environment is inherited, terminal prompts are disabled and the language is forced to english to be able to parse git messages.
+func (ExecExecutor) Run(ctx context.Context, c Command) error {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, c.Env...)
 	// set lang to english to be able to parse git messages
 	cmd.Env = append(cmd.Env, "LANG=en_US")
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
 
 	runErr := cmd.Run()
+	if runErr == nil && (cmd.ProcessState == nil || !cmd.ProcessState.Success()) {
+		return errors.New("exited with error")
+	}
+	return runErr
+}
 
-	stdOut = buffOut.String()
-	stdErr := buffErr.String()
-
-	if cmd.ProcessState == nil || !cmd.ProcessState.Success() {
-		if len(stdErr) > 0 {
-			return stdOut, errors.Errorf("%s (%v)", stdErr, runErr)
-		}
-		return stdOut, errors.Errorf("exited with error: %v", runErr)
+func (r Repo) getExecutor() Executor {
+	if r.executor != nil {
+		return r.executor
 	}
+	return ExecExecutor{}
+}
+
+func (r Repo) runCmd(ctx context.Context, name string, args ...string) (stdOut string, err error) {
+	return r.runCmdWithStdin(ctx, nil, name, args...)
+}
+
+func (r Repo) runCmdWithStdin(ctx context.Context, stdin io.Reader, name string, args ...string) (stdOut string, err error) {
+	buffOut := new(bytes.Buffer)
+	runErr := r.execCmd(ctx, Command{Name: name, Args: args, Stdin: stdin, Stdout: buffOut})
+
+	stdOut = buffOut.String()
 
 	if runErr != nil {
-		return stdOut, errors.Errorf("%s (%v)", stdErr, runErr)
+		var gitErr *GitError
+		if errors.As(runErr, &gitErr) {
+			gitErr.Stdout = redactSecrets(stdOut)
+		}
+		return stdOut, runErr
 	}
 
 	btes := []byte(stdOut)
@@ -60,3 +85,31 @@ func (r Repo) runCmd(ctx context.Context, name string, args ...string) (stdOut s
 
 	return string(btes), nil
 }
+
+// execCmd runs the command in the repo directory with the repo environment. Stdout is streamed to cmd.Stdout as it is produced.
+func (r Repo) execCmd(ctx context.Context, cmd Command) error {
+	buffErr := new(bytes.Buffer)
+	cmd.Dir = r.path
+	cmd.Stderr = buffErr
+	cmd.Env = append(cmd.Env, r.env...)
+
+	if r.sshKey != nil {
+		envs, err := r.setupSSHKey()
+		if err != nil {
+			return err
+		}
+		cmd.Env = append(cmd.Env, envs...)
+		if r.verbose {
+			r.log("Using %v\n", envs)
+		}
+	}
+
+	if r.verbose {
+		r.log("Running command %s %s\n", cmd.Name, strings.Join(cmd.Args, " "))
+	}
+
+	if runErr := r.getExecutor().Run(ctx, cmd); runErr != nil {
+		return newGitError(cmd.Name, cmd.Args, "", buffErr.String(), runErr)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommit is a commit message following the Conventional Commits specification (https://www.conventionalcommits.org)
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Body        string
+	Footers     []Footer
+	// BreakingChanges are the descriptions of the BREAKING CHANGE footers, or the description when the breaking change is only marked with a '!'
+	BreakingChanges []string
+}
+
+// Footer is a footer of a conventional commit message
+type Footer struct {
+	Token string
+	Value string
+}
+
+// ConventionalCommitError describes why a commit message does not follow the Conventional Commits specification
+type ConventionalCommitError struct {
+	Subject string
+	Reason  string
+}
+
+func (e *ConventionalCommitError) Error() string {
+	return fmt.Sprintf("invalid conventional commit %q: %s", e.Subject, e.Reason)
+}
+
+var (
+	conventionalTypeRegexp   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+	conventionalFooterRegexp = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[a-zA-Z0-9-]+)(: | #)(.*)$`)
+)
+
+// Conventional parses the commit message according to the Conventional Commits specification.
+// A *ConventionalCommitError is returned if the message does not conform.
+func (c Commit) Conventional() (ConventionalCommit, error) {
+	return ParseConventionalCommit(c.Subject, c.Body)
+}
+
+// ParseConventionalCommit parses a commit subject and body according to the Conventional Commits specification
+func ParseConventionalCommit(subject, body string) (ConventionalCommit, error) {
+	var cc ConventionalCommit
+	invalid := func(format string, args ...interface{}) (ConventionalCommit, error) {
+		return ConventionalCommit{}, &ConventionalCommitError{Subject: subject, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	subject = strings.TrimRight(subject, " \t\n")
+	if subject == "" {
+		return invalid("empty subject")
+	}
+	i := strings.Index(subject, ":")
+	if i < 0 {
+		return invalid("missing ':' after the type")
+	}
+	prefix, rest := subject[:i], subject[i+1:]
+	cc.Description = strings.TrimSpace(rest)
+	if cc.Description == "" {
+		return invalid("empty description")
+	}
+	if !strings.HasPrefix(rest, " ") {
+		return invalid("missing space after ':'")
+	}
+
+	if strings.HasSuffix(prefix, "!") {
+		cc.Breaking = true
+		prefix = strings.TrimSuffix(prefix, "!")
+	}
+	if j := strings.Index(prefix, "("); j >= 0 {
+		if !strings.HasSuffix(prefix, ")") {
+			return invalid("unterminated scope")
+		}
+		cc.Scope = prefix[j+1 : len(prefix)-1]
+		prefix = prefix[:j]
+		if strings.TrimSpace(cc.Scope) == "" {
+			return invalid("empty scope")
+		}
+		if strings.ContainsAny(cc.Scope, "()") {
+			return invalid("invalid scope %q", cc.Scope)
+		}
+	}
+	if prefix == "" {
+		return invalid("missing type")
+	}
+	if !conventionalTypeRegexp.MatchString(prefix) {
+		return invalid("invalid type %q", prefix)
+	}
+	cc.Type = prefix
+
+	cc.Body, cc.Footers = parseConventionalFooters(body)
+	for _, f := range cc.Footers {
+		if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+			if f.Value == "" {
+				return invalid("empty %s footer", f.Token)
+			}
+			cc.Breaking = true
+			cc.BreakingChanges = append(cc.BreakingChanges, f.Value)
+		}
+	}
+	if cc.Breaking && len(cc.BreakingChanges) == 0 {
+		cc.BreakingChanges = []string{cc.Description}
+	}
+	return cc, nil
+}
+
+// parseConventionalFooters splits the body and the footers. The footers are the last paragraphs beginning with a footer token.
+func parseConventionalFooters(body string) (string, []Footer) {
+	lines := strings.Split(strings.TrimRight(body, "\n"), "\n")
+	start := -1
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" || (i > 0 && strings.TrimSpace(lines[i-1]) != "") {
+			continue
+		}
+		// l is the first line of a paragraph
+		switch {
+		case !conventionalFooterRegexp.MatchString(l):
+			start = -1
+		case start < 0:
+			start = i
+		}
+	}
+	if start < 0 {
+		return strings.TrimSpace(body), nil
+	}
+
+	var footers []Footer
+	for _, l := range lines[start:] {
+		if m := conventionalFooterRegexp.FindStringSubmatch(l); m != nil {
+			footers = append(footers, Footer{Token: m[1], Value: m[3]})
+			continue
+		}
+		// continuation of the previous footer value
+		last := &footers[len(footers)-1]
+		last.Value += "\n" + l
+	}
+	for i := range footers {
+		footers[i].Value = strings.TrimSpace(footers[i].Value)
+	}
+	return strings.TrimSpace(strings.Join(lines[:start], "\n")), footers
+}
@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    ConventionalCommit
+	}{
+		{
+			name:    "type only",
+			subject: "fix: prevent racing of requests",
+			want:    ConventionalCommit{Type: "fix", Description: "prevent racing of requests"},
+		},
+		{
+			name:    "scope and breaking mark",
+			subject: "feat(api)!: send an email to the customer when a product is shipped",
+			want: ConventionalCommit{
+				Type:            "feat",
+				Scope:           "api",
+				Breaking:        true,
+				Description:     "send an email to the customer when a product is shipped",
+				BreakingChanges: []string{"send an email to the customer when a product is shipped"},
+			},
+		},
+		{
+			name:    "body and footers",
+			subject: "fix: prevent racing of requests",
+			body: "Note: introduce a request id and a reference to latest request.\n\n" +
+				"Remove timeouts which were used to mitigate the racing issue.\n\n" +
+				"Reviewed-by: Z\n" +
+				"Refs #123\n" +
+				"BREAKING CHANGE: the timeout option is removed\n" +
+				"  use the request id instead\n",
+			want: ConventionalCommit{
+				Type:        "fix",
+				Description: "prevent racing of requests",
+				Body:        "Note: introduce a request id and a reference to latest request.\n\nRemove timeouts which were used to mitigate the racing issue.",
+				Breaking:    true,
+				Footers: []Footer{
+					{Token: "Reviewed-by", Value: "Z"},
+					{Token: "Refs", Value: "123"},
+					{Token: "BREAKING CHANGE", Value: "the timeout option is removed\n  use the request id instead"},
+				},
+				BreakingChanges: []string{"the timeout option is removed\n  use the request id instead"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConventionalCommit(tt.subject, tt.body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseConventionalCommitErrors(t *testing.T) {
+	tests := []struct {
+		subject string
+		reason  string
+	}{
+		{"", "empty subject"},
+		{"Merge branch 'master'", "missing ':' after the type"},
+		{"fix:prevent racing", "missing space after ':'"},
+		{"fix: ", "empty description"},
+		{"fix(: prevent racing", "unterminated scope"},
+		{"fix(): prevent racing", "empty scope"},
+		{"(api): prevent racing", "missing type"},
+		{"fix bug: prevent racing", `invalid type "fix bug"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			_, err := Commit{Subject: tt.subject}.Conventional()
+			var ccErr *ConventionalCommitError
+			require.True(t, errors.As(err, &ccErr))
+			assert.Equal(t, tt.reason, ccErr.Reason)
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseDiffDetail parses the hunks of a single file diff
+func parseDiffDetail(diff string) (FileDiffDetail, error) {
+	var detail FileDiffDetail
+	var currentHunk *Hunk
+	// remaining lines of the current hunk, on each side
+	var oldRemaining, newRemaining int
+	var oldLineNo, newLineNo int
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			line := strings.TrimPrefix(line, "@@ ")
+			if currentHunk != nil {
+				detail.Hunks = append(detail.Hunks, *currentHunk)
+				currentHunk = nil
+			}
+			currentHunk = new(Hunk)
+			currentHunk.Header = strings.TrimSpace(strings.Split(line, "@@")[0])
+			currentHunk.Content = strings.Join(strings.Split(line, "@@")[1:], "")
+			currentHunk.Section = strings.TrimSpace(currentHunk.Content)
+			var err error
+			currentHunk.OldStart, currentHunk.OldLines, currentHunk.NewStart, currentHunk.NewLines, err = parseHunkHeader(currentHunk.Header)
+			if err != nil {
+				return detail, err
+			}
+			oldRemaining, newRemaining = currentHunk.OldLines, currentHunk.NewLines
+			oldLineNo, newLineNo = currentHunk.OldStart, currentHunk.NewStart
+		case currentHunk != nil && strings.HasPrefix(line, `\`):
+			// \ No newline at end of file
+			currentHunk.Content += "\n" + line
+			if n := len(currentHunk.Lines); n > 0 {
+				currentHunk.Lines[n-1].NoNewlineAtEOF = true
+			}
+		case currentHunk == nil || (oldRemaining <= 0 && newRemaining <= 0):
+			// outside of a hunk
+		case strings.HasPrefix(line, "-"):
+			currentHunk.RemovedLines = append(currentHunk.RemovedLines, strings.TrimPrefix(line, "-"))
+			currentHunk.Content += "\n" + line
+			currentHunk.Lines = append(currentHunk.Lines, Line{Kind: LineRemoved, Content: line[1:], OldLineNo: oldLineNo})
+			oldLineNo++
+			oldRemaining--
+		case strings.HasPrefix(line, "+"):
+			currentHunk.AddedLines = append(currentHunk.AddedLines, strings.TrimPrefix(line, "+"))
+			currentHunk.Content += "\n" + line
+			currentHunk.Lines = append(currentHunk.Lines, Line{Kind: LineAdded, Content: line[1:], NewLineNo: newLineNo})
+			newLineNo++
+			newRemaining--
+		default:
+			currentHunk.Content += "\n" + line
+			currentHunk.Lines = append(currentHunk.Lines, Line{Kind: LineContext, Content: strings.TrimPrefix(line, " "), OldLineNo: oldLineNo, NewLineNo: newLineNo})
+			oldLineNo++
+			newLineNo++
+			oldRemaining--
+			newRemaining--
+		}
+	}
+
+	if currentHunk != nil {
+		detail.Hunks = append(detail.Hunks, *currentHunk)
+	}
+
+	return detail, nil
+}
+
+// parseHunkHeader parses a "-l,s +l,s" hunk range. The length is 1 when omitted.
+func parseHunkHeader(header string) (oldStart, oldLines, newStart, newLines int, err error) {
+	parseRange := func(s, prefix string) (int, int, error) {
+		if !strings.HasPrefix(s, prefix) {
+			return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+		}
+		start, length, hasLength := strings.Cut(s[1:], ",")
+		l, err := strconv.Atoi(start)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid hunk header %q: %w", header, err)
+		}
+		if !hasLength {
+			return l, 1, nil
+		}
+		n, err := strconv.Atoi(length)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid hunk header %q: %w", header, err)
+		}
+		return l, n, nil
+	}
+	ranges := strings.Fields(header)
+	if len(ranges) != 2 {
+		return 0, 0, 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	if oldStart, oldLines, err = parseRange(ranges[0], "-"); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if newStart, newLines, err = parseRange(ranges[1], "+"); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return oldStart, oldLines, newStart, newLines, nil
+}
+
+// rawDiffEntry is an entry of the git --raw -z output, completed by the matching --numstat entry
+type rawDiffEntry struct {
+	status  string
+	srcPath string
+	dstPath string
+	srcMode string
+	dstMode string
+	srcHash string
+	dstHash string
+	stat    *numstat
+}
+
+// numstat is an entry of the git --numstat -z output
+type numstat struct {
+	additions int
+	deletions int
+	binary    bool
+}
+
+const zeroHash = "0000000000000000000000000000000000000000"
+
+// parseRawDiff parses the leading --raw -z and --numstat -z entries of data and returns the remaining bytes.
+// Combined entries (::) of merge commits are supported.
+func parseRawDiff(data []byte) ([]rawDiffEntry, []byte, error) {
+	var entries []rawDiffEntry
+	stats := make(map[string]*numstat)
+	next := func() (string, error) {
+		i := bytes.IndexByte(data, 0)
+		if i < 0 {
+			return "", fmt.Errorf("unable to parse raw diff: unterminated entry")
+		}
+		s := string(data[:i])
+		data = data[i+1:]
+		return s, nil
+	}
+
+	for {
+		data = bytes.TrimLeft(data, "\x00\n")
+		if len(data) == 0 || (data[0] != ':' && data[0] != '-' && (data[0] < '0' || data[0] > '9')) {
+			break
+		}
+		meta, err := next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if meta[0] != ':' {
+			// numstat: added\tdeleted\tpath, the path being empty for renames and copies
+			fields := strings.SplitN(meta, "\t", 3)
+			if len(fields) != 3 {
+				return nil, nil, fmt.Errorf("unable to parse numstat entry %q", meta)
+			}
+			stat := new(numstat)
+			if fields[0] == "-" && fields[1] == "-" {
+				stat.binary = true
+			} else {
+				stat.additions, _ = strconv.Atoi(fields[0])
+				stat.deletions, _ = strconv.Atoi(fields[1])
+			}
+			path := fields[2]
+			if path == "" {
+				if _, err := next(); err != nil {
+					return nil, nil, err
+				}
+				if path, err = next(); err != nil {
+					return nil, nil, err
+				}
+			}
+			stats[path] = stat
+			continue
+		}
+
+		// :srcMode dstMode srcHash dstHash status, with a mode and a hash per parent for combined entries
+		parents := len(meta) - len(strings.TrimLeft(meta, ":"))
+		fields := strings.Fields(meta[parents:])
+		if len(fields) != 2*(parents+1)+1 {
+			return nil, nil, fmt.Errorf("unable to parse raw diff entry %q", meta)
+		}
+		e := rawDiffEntry{
+			status:  fields[len(fields)-1],
+			srcMode: fields[0],
+			dstMode: fields[parents],
+			srcHash: fields[parents+1],
+			dstHash: fields[2*parents+1],
+		}
+		e.srcPath, err = next()
+		if err != nil {
+			return nil, nil, err
+		}
+		e.dstPath = e.srcPath
+		if strings.HasPrefix(e.status, "R") || strings.HasPrefix(e.status, "C") {
+			e.dstPath, err = next()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	for i := range entries {
+		entries[i].stat = stats[entries[i].dstPath]
+	}
+	return entries, data, nil
+}
+
+// file returns the File described by the raw diff entry
+func (e rawDiffEntry) file() File {
+	f := File{
+		Filename: e.dstPath,
+		Status:   e.status,
+	}
+	if e.status != "" {
+		f.Change = FileStatus(e.status[:1])
+	}
+	if f.Change == FileRenamed || f.Change == FileCopied {
+		f.OldFilename = e.srcPath
+		f.Similarity, _ = strconv.Atoi(e.status[1:])
+	}
+	if e.srcMode != "000000" {
+		f.OldMode = e.srcMode
+	}
+	if e.dstMode != "000000" {
+		f.NewMode = e.dstMode
+	}
+	if e.srcHash != zeroHash {
+		f.OldHash = e.srcHash
+	}
+	if e.dstHash != zeroHash {
+		f.NewHash = e.dstHash
+	}
+	if e.stat != nil {
+		f.IsBinary = e.stat.binary
+		f.Additions = e.stat.additions
+		f.Deletions = e.stat.deletions
+	}
+	return f
+}
+
+// parseFiles parses the --raw, --numstat and --patch -z output of git diff or git log
+func parseFiles(data []byte, opts CommitOption) (map[string]File, error) {
+	entries, patch, err := parseRawDiff(data)
+	if err != nil {
+		return nil, err
+	}
+	patches := splitPatch(string(patch))
+
+	files := make(map[string]File, len(entries))
+	for _, e := range entries {
+		if !opts.DiffOpts.matches(e.dstPath) && !opts.DiffOpts.matches(e.srcPath) {
+			continue
+		}
+		f := e.file()
+		f.Diff = patches[e.dstPath]
+		if !opts.DisableDiffDetail && !f.IsBinary && !opts.DiffOpts.WordDiff {
+			f.DiffDetail, err = parseDiffDetail(f.Diff)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compute diff on file %s: %w", f.Filename, err)
+			}
+		}
+		files[f.Filename] = f
+	}
+	return files, nil
+}
+
+// renameArgs returns the rename and copy detection arguments of git diff and git log
+func (o CommitOption) renameArgs() []string {
+	if o.DisableRenames {
+		return []string{"--no-renames"}
+	}
+	var args []string
+	switch {
+	case o.RenameThreshold > 0:
+		args = append(args, fmt.Sprintf("--find-renames=%d%%", o.RenameThreshold))
+	case o.CopyThreshold > 0:
+		args = append(args, "--find-renames")
+	}
+	if o.CopyThreshold > 0 {
+		args = append(args, fmt.Sprintf("--find-copies=%d%%", o.CopyThreshold))
+	}
+	return args
+}
+
+// args returns the git diff arguments, without the pathspecs
+func (o DiffOpts) args() []string {
+	var args []string
+	if o.Whitespace != WhitespaceDefault {
+		args = append(args, "--"+string(o.Whitespace))
+	}
+	if o.IgnoreBlankLines {
+		args = append(args, "--ignore-blank-lines")
+	}
+	if o.ContextLines != nil {
+		args = append(args, fmt.Sprintf("--unified=%d", *o.ContextLines))
+	}
+	if o.Algorithm != DiffDefault {
+		args = append(args, "--diff-algorithm="+string(o.Algorithm))
+	}
+	if o.WordDiff {
+		args = append(args, "--word-diff=plain")
+	}
+	return args
+}
+
+// pathspecs returns the git pathspecs of the included and excluded paths
+func (o DiffOpts) pathspecs() []string {
+	pathspecs := append([]string{}, o.Paths...)
+	if len(pathspecs) == 0 && len(o.ExcludePaths) > 0 {
+		pathspecs = append(pathspecs, ".")
+	}
+	for _, p := range o.ExcludePaths {
+		pathspecs = append(pathspecs, excludePathspec(p))
+	}
+	return pathspecs
+}
+
+// excludePathspec adds the exclude magic to a pathspec, keeping its own magic (ie. ":(glob)*.go" gives ":(exclude,glob)*.go")
+func excludePathspec(p string) string {
+	switch {
+	case strings.HasPrefix(p, ":("):
+		return ":(exclude," + p[2:]
+	case strings.HasPrefix(p, ":"):
+		// short form magic (ie. ":/src")
+		return ":!" + p[1:]
+	}
+	return ":(exclude)" + p
+}
+
+// checkFilePathspecs returns an error if a pathspec has magic, which is not supported to filter the files of the commits
+func (o DiffOpts) checkFilePathspecs() error {
+	for _, p := range append(append([]string{}, o.Paths...), o.ExcludePaths...) {
+		if strings.HasPrefix(p, ":") {
+			return fmt.Errorf("unsupported pathspec %q: only paths and wildcards filter the files of the commits", p)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the file is selected by the pathspecs. It is used on git log outputs, where the pathspecs would filter the commits.
+func (o DiffOpts) matches(filename string) bool {
+	for _, p := range o.ExcludePaths {
+		if matchPathspec(p, filename) {
+			return false
+		}
+	}
+	if len(o.Paths) == 0 {
+		return true
+	}
+	for _, p := range o.Paths {
+		if matchPathspec(p, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathspec matches a filename like a git pathspec without magic: the pattern is a file, a directory or a wildcard pattern
+// where '*' matches any character, including '/'.
+func matchPathspec(pattern, filename string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSuffix(pattern, "/"), "./")
+	if pattern == "" || pattern == "." || pattern == filename || strings.HasPrefix(filename, pattern+"/") {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return false
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			if j := strings.IndexByte(pattern[i:], ']'); j > 0 {
+				class := pattern[i+1 : i+j]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				expr.WriteString("[" + class + "]")
+				i += j
+				continue
+			}
+			expr.WriteString(`\[`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("(/.*)?$")
+	re, err := regexp.Compile(expr.String())
+	return err == nil && re.MatchString(filename)
+}
+
+// splitPatch splits a multi-file patch in single file patches, indexed by the destination filename
+func splitPatch(patch string) map[string]string {
+	patches := make(map[string]string)
+	var current []string
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		filename := patchFilename(current)
+		patches[filename] += strings.Join(current, "\n") + "\n"
+		current = nil
+	}
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")
+	for _, l := range lines {
+		if strings.HasPrefix(l, "diff --git ") || strings.HasPrefix(l, "diff --cc ") || strings.HasPrefix(l, "diff --combined ") {
+			flush()
+		}
+		if len(current) == 0 && !strings.HasPrefix(l, "diff ") {
+			continue
+		}
+		current = append(current, l)
+	}
+	flush()
+	return patches
+}
+
+// patchFilename returns the destination filename from the extended headers of a single file patch
+func patchFilename(lines []string) string {
+	var header, src, dst string
+	for _, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			return patchFilenameFallback(header, src, dst)
+		case strings.HasPrefix(l, "diff --cc "):
+			return unquotePath(strings.TrimPrefix(l, "diff --cc "))
+		case strings.HasPrefix(l, "diff --combined "):
+			return unquotePath(strings.TrimPrefix(l, "diff --combined "))
+		case strings.HasPrefix(l, "diff --git "):
+			header = strings.TrimPrefix(l, "diff --git ")
+		case strings.HasPrefix(l, "rename to "):
+			return unquotePath(strings.TrimPrefix(l, "rename to "))
+		case strings.HasPrefix(l, "copy to "):
+			return unquotePath(strings.TrimPrefix(l, "copy to "))
+		case strings.HasPrefix(l, "--- "):
+			src = strings.TrimSuffix(strings.TrimPrefix(l, "--- "), "\t")
+		case strings.HasPrefix(l, "+++ "):
+			dst = strings.TrimSuffix(strings.TrimPrefix(l, "+++ "), "\t")
+		case strings.HasPrefix(l, "Binary files ") && strings.HasSuffix(l, " differ"):
+			// Binary files a/foo and b/foo differ
+			paths := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(l, "Binary files "), " differ"), " and ", 2)
+			if len(paths) == 2 {
+				src, dst = paths[0], paths[1]
+			}
+		}
+	}
+	return patchFilenameFallback(header, src, dst)
+}
+
+func patchFilenameFallback(header, src, dst string) string {
+	if dst != "" && dst != "/dev/null" {
+		return strings.TrimPrefix(unquotePath(dst), "b/")
+	}
+	if src != "" && src != "/dev/null" {
+		return strings.TrimPrefix(unquotePath(src), "a/")
+	}
+	// diff --git a/foo b/foo: both paths are the same when there is no rename
+	if strings.HasPrefix(header, `"`) {
+		if i := strings.Index(header, `" `); i > 0 {
+			return strings.TrimPrefix(unquotePath(header[i+2:]), "b/")
+		}
+	}
+	if len(header)%2 == 1 {
+		return strings.TrimPrefix(header[len(header)/2+1:], "b/")
+	}
+	return header
+}
+
+// unquotePath unquotes the c-style quoted paths of git outputs
+func unquotePath(p string) string {
+	if !strings.HasPrefix(p, `"`) {
+		return p
+	}
+	s, err := strconv.Unquote(p)
+	if err != nil {
+		return p
+	}
+	return s
+}
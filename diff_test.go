@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCommitRecord(t *testing.T) {
+	record := "4a9922c6da12d70b58a7e782bb3d81650601600c\x001792189745\x00foo.bar\x00foo@bar.com\x00second\x00body here\n\x00\x00" +
+		"1792189800\x00bar.foo\x00bar@foo.com\x00821aeaa48e33373a1b61644b31a7b240092262a4\x0015f13b131919580076a3188ae86f029293954525\x00" +
+		"N\x00\x00\x00\x00\x00" +
+		"Signed-off-by\x1dfoo.bar <foo@bar.com>\x00\x00\n" +
+		":100644 100644 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 4a270318359d8c2a960136495bceeae9eee22424 M\x00bin\x00" +
+		":100644 100644 422c2b7ab3b3c668038da977e4e93a5fc623169c de980441c3ab03a8c07dda1ad27b8a11f39deb1e R066\x00f\x00g\x00" +
+		"-\t-\tbin\x00" +
+		"1\t0\t\x00f\x00g\x00\x00" +
+		"diff --git a/bin b/bin\n" +
+		"index d5d0b8b..4a27031 100644\n" +
+		"Binary files a/bin and b/bin differ\n" +
+		"diff --git a/f b/g\n" +
+		"similarity index 66%\n" +
+		"rename from f\n" +
+		"rename to g\n" +
+		"index 422c2b7..de98044 100644\n" +
+		"--- a/f\n" +
+		"+++ b/g\n" +
+		"@@ -1,2 +1,3 @@\n" +
+		" a\n" +
+		" b\n" +
+		"+c\n"
+
+	c, err := parseCommitRecord([]byte(record), CommitOption{})
+	require.NoError(t, err)
+	assert.Equal(t, "4a9922c", c.Hash)
+	assert.Equal(t, "foo.bar", c.Author)
+	assert.Equal(t, "second", c.Subject)
+	assert.Equal(t, "body here\n", c.Body)
+	assert.Equal(t, "bar.foo", c.Committer)
+	assert.Equal(t, "bar@foo.com", c.CommitterEmail)
+	assert.Equal(t, int64(1792189800), c.CommitterDate.Unix())
+	assert.Equal(t, []string{"821aeaa48e33373a1b61644b31a7b240092262a4"}, c.Parents)
+	assert.False(t, c.IsMerge)
+	assert.Equal(t, "15f13b131919580076a3188ae86f029293954525", c.TreeHash)
+	assert.Equal(t, SignatureNone, c.Signature.Status)
+	assert.Equal(t, "foo.bar <foo@bar.com>", c.Trailers.Get("Signed-off-by"))
+	require.Len(t, c.Files, 2)
+	assert.Equal(t, "M", c.Files["bin"].Status)
+	assert.Contains(t, c.Files["bin"].Diff, "Binary files a/bin and b/bin differ")
+	assert.Equal(t, FileModified, c.Files["bin"].Change)
+	assert.True(t, c.Files["bin"].IsBinary)
+	assert.Empty(t, c.Files["bin"].DiffDetail.Hunks)
+	assert.Equal(t, "d5d0b8b4c4c9e936890870f6799cfbb5ba984470", c.Files["bin"].OldHash)
+	assert.Equal(t, "4a270318359d8c2a960136495bceeae9eee22424", c.Files["bin"].NewHash)
+	assert.Equal(t, 1, c.Files["g"].Additions)
+	assert.Equal(t, 0, c.Files["g"].Deletions)
+	assert.False(t, c.Files["g"].IsBinary)
+	assert.Equal(t, "R066", c.Files["g"].Status)
+	assert.Equal(t, FileRenamed, c.Files["g"].Change)
+	assert.Equal(t, "f", c.Files["g"].OldFilename)
+	assert.Equal(t, 66, c.Files["g"].Similarity)
+	require.Len(t, c.Files["g"].DiffDetail.Hunks, 1)
+	assert.Equal(t, []string{"c"}, c.Files["g"].DiffDetail.Hunks[0].AddedLines)
+
+	// a record separator in the message does not start a new commit
+	message := "4a9922c6da12d70b58a7e782bb3d81650601600c\x001792189745\x00foo.bar\x00foo@bar.com\x00\x1esubject\x00body\n\x1eline\n\x00\x00" +
+		"1792189800\x00bar.foo\x00bar@foo.com\x00\x0015f13b131919580076a3188ae86f029293954525\x00" +
+		"N\x00\x00\x00\x00\x00\x00\n"
+	dec := newCommitDecoder(strings.NewReader("\x1e" + message + "\x1e" + record))
+	c, err = dec.next(CommitOption{})
+	require.NoError(t, err)
+	assert.Equal(t, "\x1esubject", c.Subject)
+	assert.Equal(t, "body\n\x1eline\n", c.Body)
+	assert.Empty(t, c.Files)
+	c, err = dec.next(CommitOption{})
+	require.NoError(t, err)
+	assert.Equal(t, "second", c.Subject)
+	assert.Len(t, c.Files, 2)
+	_, err = dec.next(CommitOption{})
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_parseDiffDetail(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n" +
+		"index 422c2b7..de98044 100644\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,4 +1,4 @@ package main\n" +
+		" a\n" +
+		"-b\n" +
+		"+B\n" +
+		" c\n" +
+		" d\n" +
+		"@@ -10 +10,2 @@ func main() {\n" +
+		"--- e\n" +
+		"+++ e\n" +
+		"+f\n" +
+		"\\ No newline at end of file\n"
+
+	detail, err := parseDiffDetail(diff)
+	require.NoError(t, err)
+	require.Len(t, detail.Hunks, 2)
+
+	h := detail.Hunks[0]
+	assert.Equal(t, "-1,4 +1,4", h.Header)
+	assert.Equal(t, "package main", h.Section)
+	assert.Equal(t, []int{1, 4, 1, 4}, []int{h.OldStart, h.OldLines, h.NewStart, h.NewLines})
+	assert.Equal(t, []string{"b"}, h.RemovedLines)
+	assert.Equal(t, []string{"B"}, h.AddedLines)
+	assert.Equal(t, []Line{
+		{Kind: LineContext, Content: "a", OldLineNo: 1, NewLineNo: 1},
+		{Kind: LineRemoved, Content: "b", OldLineNo: 2},
+		{Kind: LineAdded, Content: "B", NewLineNo: 2},
+		{Kind: LineContext, Content: "c", OldLineNo: 3, NewLineNo: 3},
+		{Kind: LineContext, Content: "d", OldLineNo: 4, NewLineNo: 4},
+	}, h.Lines)
+
+	h = detail.Hunks[1]
+	assert.Equal(t, []int{10, 1, 10, 2}, []int{h.OldStart, h.OldLines, h.NewStart, h.NewLines})
+	assert.Equal(t, []Line{
+		{Kind: LineRemoved, Content: "-- e", OldLineNo: 10},
+		{Kind: LineAdded, Content: "++ e", NewLineNo: 10},
+		{Kind: LineAdded, Content: "f", NewLineNo: 11, NoNewlineAtEOF: true},
+	}, h.Lines)
+	assert.Equal(t, " func main() {\n--- e\n+++ e\n+f\n\\ No newline at end of file", h.Content)
+}
+
+func Test_parseHunkHeader(t *testing.T) {
+	oldStart, oldLines, newStart, newLines, err := parseHunkHeader("-0,0 +1,3")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 0, 1, 3}, []int{oldStart, oldLines, newStart, newLines})
+
+	_, _, _, _, err = parseHunkHeader("-1,2 -1,2 +1,3")
+	assert.Error(t, err)
+	_, _, _, _, err = parseHunkHeader("-a +1")
+	assert.Error(t, err)
+}
+
+func TestFileDiffDetail_Grep(t *testing.T) {
+	detail, err := parseDiffDetail("@@ -1,3 +1,3 @@\n" +
+		" password = ''\n" +
+		"-token = ''\n" +
+		"+token = 'ghp_0123456789'\n" +
+		" other\n" +
+		"@@ -10,2 +10,1 @@\n" +
+		"-secret = 'ghp_9876543210'\n" +
+		" end\n")
+	require.NoError(t, err)
+
+	re := regexp.MustCompile(`ghp_[0-9]+`)
+	hunks, added, removed := detail.Matches(re)
+	assert.Len(t, hunks, 2)
+	assert.True(t, added)
+	assert.True(t, removed)
+
+	hunks, _, _ = detail.Matches(regexp.MustCompile(`password`))
+	assert.Empty(t, hunks, "context lines must not match")
+
+	matches := File{Filename: "config.py", DiffDetail: detail}.Grep(re)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "config.py", matches[0].Filename)
+	assert.Equal(t, LineAdded, matches[0].Side)
+	assert.Equal(t, 2, matches[0].LineNo)
+	assert.Equal(t, "token = 'ghp_0123456789'", matches[0].Line.Content)
+	assert.Equal(t, 1, matches[0].Hunk.NewStart)
+	assert.Equal(t, LineRemoved, matches[1].Side)
+	assert.Equal(t, 10, matches[1].LineNo)
+}
+
+func TestCommitOption_renameArgs(t *testing.T) {
+	assert.Empty(t, CommitOption{}.renameArgs())
+	assert.Equal(t, []string{"--no-renames"}, CommitOption{DisableRenames: true, RenameThreshold: 90}.renameArgs())
+	assert.Equal(t, []string{"--find-renames=90%"}, CommitOption{RenameThreshold: 90}.renameArgs())
+	assert.Equal(t, []string{"--find-renames", "--find-copies=75%"}, CommitOption{CopyThreshold: 75}.renameArgs())
+	assert.Equal(t, []string{"--find-renames=90%", "--find-copies=75%"}, CommitOption{RenameThreshold: 90, CopyThreshold: 75}.renameArgs())
+}
+
+func Test_parseRawDiffCombined(t *testing.T) {
+	// git outputs the numstat of merge commits, against the first parent, before the combined raw entries
+	data := "1\t1\ts\x00" +
+		"::100644 100644 100755 587be6b4c3f93f93c489c0111bba5596147a26cb 975fbec8256d3e8a3797e7a3611380f27c49f4ac b68025345d5301abad4d9ec9166f455243a0d746 MM\x00s\x00\x00" +
+		"diff --cc s\n"
+	entries, rest, err := parseRawDiff([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	f := entries[0].file()
+	assert.Equal(t, "s", f.Filename)
+	assert.Equal(t, FileModified, f.Change)
+	assert.Equal(t, "100644", f.OldMode)
+	assert.Equal(t, "100755", f.NewMode)
+	assert.Equal(t, "587be6b4c3f93f93c489c0111bba5596147a26cb", f.OldHash)
+	assert.Equal(t, "b68025345d5301abad4d9ec9166f455243a0d746", f.NewHash)
+	assert.Equal(t, 1, f.Additions)
+	assert.Equal(t, "diff --cc s\n", string(rest))
+}
+
+func TestDiffOpts_args(t *testing.T) {
+	zero := 0
+	opts := DiffOpts{
+		Whitespace:       IgnoreAllSpace,
+		IgnoreBlankLines: true,
+		ContextLines:     &zero,
+		Algorithm:        DiffHistogram,
+		WordDiff:         true,
+		ExcludePaths:     []string{"vendor"},
+	}
+	assert.Equal(t, []string{"--ignore-all-space", "--ignore-blank-lines", "--unified=0", "--diff-algorithm=histogram", "--word-diff=plain"}, opts.args())
+	assert.Equal(t, []string{".", ":(exclude)vendor"}, opts.pathspecs())
+	opts = DiffOpts{Paths: []string{"src"}, ExcludePaths: []string{":(glob)**/*.go", ":/vendor"}}
+	assert.Equal(t, []string{"src", ":(exclude,glob)**/*.go", ":!/vendor"}, opts.pathspecs())
+	assert.Empty(t, DiffOpts{}.args())
+	assert.Empty(t, DiffOpts{}.pathspecs())
+}
+
+func Test_matchPathspec(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		filename string
+		want     bool
+	}{
+		{"repo.go", "repo.go", true},
+		{"repo.go", "repo.go.orig", false},
+		{"vendor", "vendor/github.com/pkg/errors/errors.go", true},
+		{"vendor/", "vendor/modules.txt", true},
+		{"./vendor", "vendor/modules.txt", true},
+		{"vendor", "vendors.go", false},
+		{"*.go", "cmd/main.go", true},
+		{"*.go", "go.mod", false},
+		{"cmd/*", "cmd/repo/main.go", true},
+		{"repo_?est.go", "repo_test.go", true},
+		{"[a-c]*.go", "bare.go", true},
+		{"[!a-c]*.go", "bare.go", false},
+		{".", "anything", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.filename, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchPathspec(tt.pattern, tt.filename))
+		})
+	}
+}
+
+func Test_parseCommitRecordWithPathspecs(t *testing.T) {
+	record := "4a9922c6da12d70b58a7e782bb3d81650601600c\x001792189745\x00foo.bar\x00foo@bar.com\x00second\x00\x00\x00" +
+		"1792189800\x00bar.foo\x00bar@foo.com\x00\x0015f13b131919580076a3188ae86f029293954525\x00" +
+		"N\x00\x00\x00\x00\x00\x00\n" +
+		":000000 100644 0000000000000000000000000000000000000000 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 A\x00main.go\x00" +
+		":000000 100644 0000000000000000000000000000000000000000 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 A\x00vendor/lib.go\x00" +
+		":000000 100644 0000000000000000000000000000000000000000 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 A\x00README.md\x00\x00"
+
+	c, err := parseCommitRecord([]byte(record), CommitOption{DiffOpts: DiffOpts{Paths: []string{"*.go"}, ExcludePaths: []string{"vendor"}}})
+	require.NoError(t, err)
+	require.Len(t, c.Files, 1)
+	assert.Contains(t, c.Files, "main.go")
+}
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// GitErrorKind classifies the failure of a git command
+type GitErrorKind int
+
+const (
+	UnknownError GitErrorKind = iota
+	AuthFailed
+	RefNotFound
+	Conflict
+	NotARepository
+	NetworkError
+	NonFastForward
+)
+
+func (k GitErrorKind) String() string {
+	switch k {
+	case AuthFailed:
+		return "AuthFailed"
+	case RefNotFound:
+		return "RefNotFound"
+	case Conflict:
+		return "Conflict"
+	case NotARepository:
+		return "NotARepository"
+	case NetworkError:
+		return "NetworkError"
+	case NonFastForward:
+		return "NonFastForward"
+	}
+	return "UnknownError"
+}
+
+// GitError is returned by all the Repo and BareRepo methods when a git command fails. Credentials are redacted from Args, Stdout and Stderr.
+type GitError struct {
+	Args     []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Kind     GitErrorKind
+	Err      error
+}
+
+func (e *GitError) Error() string {
+	if len(e.Stderr) > 0 {
+		return fmt.Sprintf("%s (%v)", e.Stderr, e.Err)
+	}
+	return fmt.Sprintf("exited with error: %v", e.Err)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// IsGitErrorKind returns true if err is, or wraps, a *GitError of the given kind
+func IsGitErrorKind(err error, kind GitErrorKind) bool {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		return false
+	}
+	return gitErr.Kind == kind
+}
+
+func newGitError(name string, args []string, stdOut, stdErr string, runErr error) *GitError {
+	e := &GitError{
+		Args:     make([]string, 0, len(args)+1),
+		ExitCode: -1,
+		Stdout:   redactSecrets(stdOut),
+		Stderr:   redactSecrets(stdErr),
+		Err:      runErr,
+	}
+	e.Args = append(e.Args, name)
+	for _, a := range args {
+		e.Args = append(e.Args, redactSecrets(a))
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(runErr, &exitErr) {
+		e.ExitCode = exitErr.ExitCode()
+	}
+	e.Kind = classifyGitError(stdErr)
+	return e
+}
+
+// gitErrorPatterns are evaluated in order, the first match wins
+var gitErrorPatterns = []struct {
+	kind     GitErrorKind
+	patterns []string
+}{
+	{AuthFailed, []string{
+		"authentication failed",
+		"permission denied (publickey",
+		"could not read username",
+		"could not read password",
+		"terminal prompts disabled",
+		"invalid username or password",
+		"http basic: access denied",
+		"the requested url returned error: 401",
+		"the requested url returned error: 403",
+	}},
+	{NotARepository, []string{
+		"not a git repository",
+		"does not appear to be a git repository",
+		"repository not found",
+	}},
+	{NonFastForward, []string{
+		"non-fast-forward",
+		"[rejected]",
+		"fetch first",
+		"not possible to fast-forward",
+		"updates were rejected",
+	}},
+	{Conflict, []string{
+		"conflict",
+		"would be overwritten",
+		"needs merge",
+		"resolve your current index first",
+		"unmerged files",
+	}},
+	{RefNotFound, []string{
+		"unknown revision",
+		"not a valid object name",
+		"couldn't find remote ref",
+		"did not match any file(s) known to git",
+		"bad revision",
+		"needed a single revision",
+		"invalid reference",
+		"not a valid ref",
+		"no such ref",
+		"bad object",
+		"not a tree object",
+	}},
+	{NetworkError, []string{
+		"could not resolve host",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"network is unreachable",
+		"failed to connect",
+		"connection reset",
+		"unable to access",
+		"could not read from remote repository",
+		"the remote end hung up unexpectedly",
+		"early eof",
+	}},
+}
+
+func classifyGitError(stdErr string) GitErrorKind {
+	s := strings.ToLower(stdErr)
+	for _, p := range gitErrorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(s, pattern) {
+				return p.kind
+			}
+		}
+	}
+	return UnknownError
+}
+
+var urlRegExp = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"]+`)
+
+// redactSecrets removes the user info from all the urls found in s
+func redactSecrets(s string) string {
+	return urlRegExp.ReplaceAllStringFunc(s, func(rawURL string) string {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.User == nil {
+			return rawURL
+		}
+		u.User = nil
+		return u.String()
+	})
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+)
+
+type FileHistoryOpts struct {
+	// Revision is the revision to start from, HEAD if empty
+	Revision string
+	MaxCount int
+	// NoFollow stops the history at the renames of the file
+	NoFollow bool
+	CommitOption
+}
+
+// FileRevision is a commit which touched a file
+type FileRevision struct {
+	Commit
+	// Path is the path of the file at this commit
+	Path string
+	// File is the change of the file in the commit
+	File File
+}
+
+// FileHistory returns the commits which touched the file, from the newest, following its renames
+func (r Repo) FileHistory(ctx context.Context, path string, opts FileHistoryOpts) ([]FileRevision, error) {
+	var revisions []FileRevision
+	it := r.FileHistoryIter(ctx, path, opts)
+	defer it.Close()
+	for it.Next() {
+		revisions = append(revisions, it.Revision())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// FileHistoryIter returns an iterator on the commits which touched the file, from the newest, following its renames
+func (r Repo) FileHistoryIter(ctx context.Context, path string, opts FileHistoryOpts) *FileHistoryIter {
+	var args []string
+	if !opts.NoFollow {
+		args = append(args, "--follow")
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.MaxCount))
+	}
+	if opts.Revision == "" {
+		opts.Revision = "HEAD"
+	}
+	args = append(args, opts.Revision, "--", path)
+	return &FileHistoryIter{commits: r.logIter(ctx, nil, opts.CommitOption, args...), path: path}
+}
+
+// FileHistoryIter iterates over the history of a file. Close must be called if the iteration is stopped before its end.
+type FileHistoryIter struct {
+	commits  *CommitIter
+	path     string
+	revision FileRevision
+}
+
+// Next advances the iterator to the next commit, it returns false at the end of the iteration or on error
+func (it *FileHistoryIter) Next() bool {
+	if !it.commits.Next() {
+		return false
+	}
+	c := it.commits.Commit()
+	it.revision = FileRevision{Commit: c, Path: it.path}
+	f, has := c.Files[it.path]
+	if !has && len(c.Files) == 1 {
+		// git followed the file under another name
+		for _, file := range c.Files {
+			f, has = file, true
+		}
+	}
+	if has {
+		it.revision.Path = f.Filename
+		it.revision.File = f
+		if f.OldFilename != "" {
+			// the file had its old name before this commit
+			it.path = f.OldFilename
+		}
+	}
+	return true
+}
+
+// Revision returns the current commit
+func (it *FileHistoryIter) Revision() FileRevision {
+	return it.revision
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *FileHistoryIter) Err() error {
+	return it.commits.Err()
+}
+
+// Close stops the underlying git command
+func (it *FileHistoryIter) Close() error {
+	return it.commits.Close()
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHistory(t *testing.T) {
+	content := "package repo\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n"
+	r := initTestRepo(t, map[string]string{"old.go": content, "other.go": "package repo\n"})
+	commitTestFiles(t, r, "second", map[string]string{"old.go": content + "\nfunc d() {}\n"})
+	_, err := r.runCmd(context.TODO(), "git", "mv", "old.go", "new.go")
+	require.NoError(t, err)
+	require.NoError(t, r.Commit(context.TODO(), "rename"))
+	commitTestFiles(t, r, "fourth", map[string]string{"new.go": content + "\nfunc e() {}\n", "other.go": "package repo\n\n"})
+
+	revisions, err := r.FileHistory(context.TODO(), "new.go", FileHistoryOpts{})
+	require.NoError(t, err)
+	require.Len(t, revisions, 4)
+	assert.Equal(t, []string{"new.go", "new.go", "old.go", "old.go"}, []string{revisions[0].Path, revisions[1].Path, revisions[2].Path, revisions[3].Path})
+	assert.Equal(t, FileModified, revisions[0].File.Change)
+	require.Len(t, revisions[0].Files, 1, "only the followed file is reported")
+	assert.Equal(t, FileRenamed, revisions[1].File.Change)
+	assert.Equal(t, "old.go", revisions[1].File.OldFilename)
+	assert.Equal(t, FileModified, revisions[2].File.Change)
+	assert.Equal(t, []string{"", "func d() {}"}, revisions[2].File.DiffDetail.Hunks[0].AddedLines)
+	assert.Equal(t, FileAdded, revisions[3].File.Change)
+
+	revisions, err = r.FileHistory(context.TODO(), "new.go", FileHistoryOpts{NoFollow: true})
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	it := r.FileHistoryIter(context.TODO(), "new.go", FileHistoryOpts{MaxCount: 1})
+	defer it.Close()
+	require.True(t, it.Next())
+	assert.Equal(t, "new.go", it.Revision().Path)
+	assert.False(t, it.Next())
+	require.NoError(t, it.Err())
+}
@@ -0,0 +1,532 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogOrder is the order of the commits returned by Log
+type LogOrder string
+
+const (
+	DefaultOrder    LogOrder = ""
+	DateOrder       LogOrder = "date"
+	AuthorDateOrder LogOrder = "author-date"
+	TopoOrder       LogOrder = "topo"
+)
+
+// LogOpts are the filters of a Log query. Author, Committer and Grep are POSIX extended regular expressions.
+type LogOpts struct {
+	// Revisions are the revisions or ranges (ie. v1.0.0..HEAD) to walk, HEAD if empty
+	Revisions   []string
+	Author      string
+	Committer   string
+	Grep        string
+	Paths       []string
+	FirstParent bool
+	NoMerges    bool
+	MergesOnly  bool
+	MaxCount    int
+	Skip        int
+	Order       LogOrder
+	Reverse     bool
+	CommitOption
+}
+
+func (o LogOpts) args() ([]string, error) {
+	if o.NoMerges && o.MergesOnly {
+		return nil, fmt.Errorf("invalid log options: NoMerges and MergesOnly are mutually exclusive")
+	}
+	var args []string
+	if o.Author != "" || o.Committer != "" || o.Grep != "" {
+		args = append(args, "--extended-regexp")
+	}
+	if o.Author != "" {
+		args = append(args, "--author="+o.Author)
+	}
+	if o.Committer != "" {
+		args = append(args, "--committer="+o.Committer)
+	}
+	if o.Grep != "" {
+		args = append(args, "--grep="+o.Grep)
+	}
+	if o.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if o.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	if o.MergesOnly {
+		args = append(args, "--merges")
+	}
+	if o.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(o.MaxCount))
+	}
+	if o.Skip > 0 {
+		args = append(args, "--skip="+strconv.Itoa(o.Skip))
+	}
+	switch o.Order {
+	case DefaultOrder:
+	case DateOrder, AuthorDateOrder, TopoOrder:
+		args = append(args, "--"+string(o.Order)+"-order")
+	default:
+		return nil, fmt.Errorf("invalid log order %q", o.Order)
+	}
+	if o.Reverse {
+		args = append(args, "--reverse")
+	}
+	if len(o.Revisions) == 0 {
+		args = append(args, "HEAD")
+	}
+	args = append(args, o.Revisions...)
+	if len(o.Paths) > 0 {
+		// keep all the files of the commits, not only the ones matching the paths
+		args = append(args, "--full-diff")
+	}
+	args = append(args, "--")
+	args = append(args, o.Paths...)
+	return args, nil
+}
+
+// Log returns the commits matching the query
+func (r Repo) Log(ctx context.Context, opts LogOpts) ([]Commit, error) {
+	args, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	return r.collectCommits(ctx, nil, opts.CommitOption, args...)
+}
+
+// LogIter returns an iterator on the commits matching the query
+func (r Repo) LogIter(ctx context.Context, opts LogOpts) *CommitIter {
+	args, err := opts.args()
+	if err != nil {
+		return errCommitIter(err)
+	}
+	return r.logIter(ctx, nil, opts.CommitOption, args...)
+}
+
+// logFormat is the pretty format used to decode the git log output. Each commit starts with a record separator and its fields are NUL terminated.
+const logFormat = "%x1e%H%x00%at%x00%an%x00%ae%x00%s%x00%b%x00%GK%x00" +
+	"%ct%x00%cn%x00%ce%x00%P%x00%T%x00" +
+	"%G?%x00%GS%x00%GF%x00%GP%x00%GG%x00" +
+	"%(trailers:only,unfold,separator=%x1f,key_value_separator=%x1d)%x00"
+
+// logFormatFields is the number of fields in logFormat
+const logFormatFields = 18
+
+// logArgs returns the git log arguments producing the commits, their raw diff and their patch
+func logArgs(opts CommitOption, args ...string) []string {
+	logArgs := []string{"log", "-z", "--format=" + logFormat, "--no-color", "--no-show-signature"}
+	if !opts.DisableFiles {
+		logArgs = append(logArgs,
+			"--raw", "--numstat", "-p", "--cc", "--no-abbrev",
+			"--src-prefix=a/", "--dst-prefix=b/", "--no-ext-diff",
+		)
+		logArgs = append(logArgs, opts.renameArgs()...)
+		logArgs = append(logArgs, opts.DiffOpts.args()...)
+	}
+	return append(logArgs, args...)
+}
+
+// CommitIter iterates over commits as git outputs them, without holding the whole history in memory.
+// Iteration stops when the context is cancelled. Close must be called if the iteration is stopped before its end.
+//
+//	it := r.CommitsIter(ctx, "", "HEAD")
+//	defer it.Close()
+//	for it.Next() {
+//		c := it.Commit()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type CommitIter struct {
+	repo   Repo
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   CommitOption
+	dec    *commitDecoder
+	pr     *io.PipeReader
+	cmdErr chan error
+	commit Commit
+	err    error
+	done   bool
+	// objects reads the signed commit objects, it is started on the first signed commit
+	objects *objectBatch
+}
+
+// logIter runs git log with args in background and returns an iterator on its output
+func (r Repo) logIter(ctx context.Context, stdin io.Reader, opts CommitOption, args ...string) *CommitIter {
+	if !opts.DisableFiles {
+		if err := opts.DiffOpts.checkFilePathspecs(); err != nil {
+			return errCommitIter(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	it := &CommitIter{
+		repo:   r,
+		ctx:    ctx,
+		cancel: cancel,
+		opts:   opts,
+		dec:    newCommitDecoder(pr),
+		pr:     pr,
+		cmdErr: make(chan error, 1),
+	}
+	go func() {
+		err := r.execCmd(ctx, Command{Name: "git", Args: logArgs(opts, args...), Stdin: stdin, Stdout: pw})
+		pw.CloseWithError(err)
+		it.cmdErr <- err
+	}()
+	return it
+}
+
+// errCommitIter returns an iterator which fails immediately
+func errCommitIter(err error) *CommitIter {
+	return &CommitIter{err: err, done: true}
+}
+
+// Next advances the iterator to the next commit, it returns false at the end of the iteration or on error
+func (it *CommitIter) Next() bool {
+	if it.done {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.stop(err, false)
+		return false
+	}
+	c, err := it.dec.next(it.opts)
+	if err == io.EOF {
+		it.stop(nil, true)
+		return false
+	}
+	if err == nil && c.Signature.Status != SignatureNone && c.Signature.Status != "" {
+		if it.objects == nil {
+			it.objects = it.repo.newObjectBatch(it.ctx)
+		}
+		var object string
+		object, err = it.objects.read(c.LongHash)
+		c.Signature.Raw = commitSignature(object)
+		if err == nil && it.opts.LoadTrustLevel {
+			c.Signature.TrustLevel, err = it.repo.commitTrustLevel(it.ctx, c.LongHash, c.Signature.Status)
+		}
+	}
+	if err != nil {
+		it.stop(err, false)
+		return false
+	}
+	it.commit = c
+	return true
+}
+
+// Commit returns the current commit
+func (it *CommitIter) Commit() Commit {
+	return it.commit
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *CommitIter) Err() error {
+	return it.err
+}
+
+// Close stops the underlying git command
+func (it *CommitIter) Close() error {
+	if !it.done {
+		it.stop(nil, false)
+	}
+	return nil
+}
+
+// stop stops git log, cmdErr is reported only if the whole output has been read
+func (it *CommitIter) stop(err error, eof bool) {
+	it.done = true
+	ctxErr := it.ctx.Err()
+	it.cancel()
+	it.pr.CloseWithError(io.ErrClosedPipe)
+	cmdErr := <-it.cmdErr
+	if it.objects != nil {
+		it.objects.close()
+	}
+	switch {
+	case ctxErr != nil:
+		it.err = ctxErr
+	case err != nil:
+		it.err = err
+	case eof:
+		it.err = cmdErr
+	}
+}
+
+// TagIter iterates over the tags as git outputs their commits. Close must be called if the iteration is stopped before its end.
+type TagIter struct {
+	commits *CommitIter
+	names   []string
+	tags    map[string][]string
+	pending []Tag
+	tag     Tag
+}
+
+// Next advances the iterator to the next tag, it returns false at the end of the iteration or on error
+func (it *TagIter) Next() bool {
+	for len(it.pending) == 0 {
+		if !it.commits.Next() {
+			return false
+		}
+		c := it.commits.Commit()
+		for _, t := range it.tags[c.LongHash] {
+			it.pending = append(it.pending, Tag{Commit: c, Message: t})
+		}
+	}
+	it.tag, it.pending = it.pending[0], it.pending[1:]
+	return true
+}
+
+// Tag returns the current tag
+func (it *TagIter) Tag() Tag {
+	return it.tag
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *TagIter) Err() error {
+	return it.commits.Err()
+}
+
+// Close stops the underlying git command
+func (it *TagIter) Close() error {
+	return it.commits.Close()
+}
+
+// logCommits runs git log with args and calls fn for each commit as soon as git outputs it.
+// git log is stopped as soon as fn returns an error.
+func (r Repo) logCommits(ctx context.Context, stdin io.Reader, opts CommitOption, fn func(Commit) error, args ...string) error {
+	it := r.logIter(ctx, stdin, opts, args...)
+	defer it.Close()
+	for it.Next() {
+		if err := fn(it.Commit()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// commitDecoder decodes the git log output produced by logArgs
+type commitDecoder struct {
+	r       *bufio.Reader
+	started bool
+}
+
+func newCommitDecoder(r io.Reader) *commitDecoder {
+	return &commitDecoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// nextRecord returns the bytes of the next commit. The format fields are read first, as they may contain record separators.
+// In the files which follow, a record separator is only considered as the start of a commit at the beginning of a line or after a NUL byte.
+func (d *commitDecoder) nextRecord() ([]byte, error) {
+	if !d.started {
+		if _, err := d.r.ReadBytes('\x1e'); err != nil {
+			return nil, err
+		}
+		d.started = true
+	}
+	var record []byte
+	for i := 0; i < logFormatFields; i++ {
+		field, err := d.r.ReadBytes(0)
+		record = append(record, field...)
+		if err == io.EOF {
+			if len(record) == 0 {
+				return nil, io.EOF
+			}
+			// the record is incomplete, parseCommitRecord reports it
+			return record, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for {
+		chunk, err := d.r.ReadBytes('\x1e')
+		record = append(record, chunk...)
+		if err == io.EOF {
+			return record, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		n := len(record)
+		if record[n-2] == '\n' || record[n-2] == 0 {
+			return record[:n-1], nil
+		}
+	}
+}
+
+func (d *commitDecoder) next(opts CommitOption) (Commit, error) {
+	record, err := d.nextRecord()
+	if err != nil {
+		return Commit{}, err
+	}
+	return parseCommitRecord(record, opts)
+}
+
+func parseCommitRecord(record []byte, opts CommitOption) (Commit, error) {
+	var c Commit
+	fields := make([]string, logFormatFields)
+	for i := range fields {
+		j := bytes.IndexByte(record, 0)
+		if j < 0 {
+			return c, fmt.Errorf("unable to parse commit: %d fields expected", logFormatFields)
+		}
+		fields[i] = string(record[:j])
+		record = record[j+1:]
+	}
+
+	c.LongHash = fields[0]
+	if len(c.LongHash) >= 7 {
+		c.Hash = c.LongHash[:7]
+	}
+	ts, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return c, err
+	}
+	c.Date = time.Unix(ts, 0)
+	c.Author = fields[2]
+	c.AuthorEmail = fields[3]
+	c.Subject = fields[4]
+	c.Body = fields[5]
+	c.GPGKeyID = fields[6]
+	ts, err = strconv.ParseInt(fields[7], 10, 64)
+	if err != nil {
+		return c, err
+	}
+	c.CommitterDate = time.Unix(ts, 0)
+	c.Committer = fields[8]
+	c.CommitterEmail = fields[9]
+	c.Parents = strings.Fields(fields[10])
+	c.IsMerge = len(c.Parents) > 1
+	c.TreeHash = fields[11]
+	c.Signature = Signature{
+		Status:                SignatureStatus(fields[12]),
+		Signer:                fields[13],
+		KeyID:                 c.GPGKeyID,
+		Fingerprint:           fields[14],
+		PrimaryKeyFingerprint: fields[15],
+		Message:               fields[16],
+	}
+	c.Trailers = parseTrailers(fields[17])
+
+	c.Files, err = parseFiles(record, opts)
+	if err != nil {
+		return c, fmt.Errorf("unable to parse commit %s: %w", c.LongHash, err)
+	}
+	return c, nil
+}
+
+// commitTrustLevel returns the trust level of the key which made a good signature.
+// It is not part of logFormat because git aborts on %GT when the signature can't be checked.
+func (r Repo) commitTrustLevel(ctx context.Context, hash string, status SignatureStatus) (string, error) {
+	if status != SignatureGood && status != SignatureUnknownValidity {
+		return "", nil
+	}
+	trust, err := r.runCmd(ctx, "git", "log", "--max-count=1", "--format=%GT", hash, "--")
+	if err != nil {
+		return "", fmt.Errorf("unable to get the signature trust level of commit %s: %w", hash, err)
+	}
+	return strings.TrimSpace(trust), nil
+}
+
+// commitSignature returns the armored signature stored in the header of a commit object
+func commitSignature(object string) string {
+	var signature []string
+	var inSignature bool
+	for _, l := range strings.Split(object, "\n") {
+		switch {
+		case l == "":
+			// end of the header
+			return strings.Join(signature, "\n")
+		case strings.HasPrefix(l, "gpgsig ") || strings.HasPrefix(l, "gpgsig-sha256 "):
+			inSignature = true
+			signature = append(signature, l[strings.Index(l, " ")+1:])
+		case inSignature && strings.HasPrefix(l, " "):
+			signature = append(signature, l[1:])
+		default:
+			inSignature = false
+		}
+	}
+	return strings.Join(signature, "\n")
+}
+
+// objectBatch reads objects through a long-lived git cat-file --batch process
+type objectBatch struct {
+	stdin  *io.PipeWriter
+	stdout *bufio.Reader
+	done   chan error
+}
+
+func (r Repo) newObjectBatch(ctx context.Context) *objectBatch {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	b := &objectBatch{stdin: inW, stdout: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := r.execCmd(ctx, Command{Name: "git", Args: []string{"cat-file", "--batch"}, Stdin: inR, Stdout: outW})
+		// unblock the pending reads and writes
+		outW.CloseWithError(err)
+		inR.CloseWithError(io.ErrClosedPipe)
+		b.done <- err
+	}()
+	return b
+}
+
+// read returns the content of an object
+func (b *objectBatch) read(hash string) (string, error) {
+	if _, err := io.WriteString(b.stdin, hash+"\n"); err != nil {
+		return "", fmt.Errorf("unable to read object %s: %w", hash, err)
+	}
+	// <hash> <type> <size>\n<content>\n, or <hash> missing\n
+	header, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("unable to read object %s: %w", hash, err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return "", fmt.Errorf("unable to read object %s: %s", hash, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("unable to read object %s: %w", hash, err)
+	}
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(b.stdout, content); err != nil {
+		return "", fmt.Errorf("unable to read object %s: %w", hash, err)
+	}
+	return string(content[:size]), nil
+}
+
+// close stops git cat-file
+func (b *objectBatch) close() error {
+	b.stdin.Close()
+	return <-b.done
+}
+
+// collectCommits runs git log and returns all the commits
+func (r Repo) collectCommits(ctx context.Context, stdin io.Reader, opts CommitOption, args ...string) ([]Commit, error) {
+	var commits []Commit
+	err := r.logCommits(ctx, stdin, opts, func(c Commit) error {
+		commits = append(commits, c)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// revisionsReader returns a reader to feed git --stdin with revisions
+func revisionsReader(revs []string) io.Reader {
+	return strings.NewReader(strings.Join(revs, "\n") + "\n")
+}
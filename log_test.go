@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	r, err := New(context.TODO(), ".")
+	require.NoError(t, err)
+
+	latest, err := r.LatestCommit(context.TODO(), CommitOption{DisableDiffDetail: true})
+	require.NoError(t, err)
+
+	commits, err := r.Log(context.TODO(), LogOpts{MaxCount: 1, CommitOption: CommitOption{DisableDiffDetail: true}})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, latest, commits[0])
+
+	commits, err = r.Log(context.TODO(), LogOpts{Author: "^" + regexp.QuoteMeta(latest.Author) + " <", Grep: regexp.QuoteMeta(latest.Subject), MaxCount: 1, FirstParent: true, NoMerges: true, Order: TopoOrder})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, latest.LongHash, commits[0].LongHash)
+
+	commits, err = r.Log(context.TODO(), LogOpts{Author: "this author does not exist"})
+	require.NoError(t, err)
+	assert.Empty(t, commits)
+
+	all, err := r.Log(context.TODO(), LogOpts{Paths: []string{"repo.go"}, Order: DateOrder})
+	require.NoError(t, err)
+	skipped, err := r.Log(context.TODO(), LogOpts{Paths: []string{"repo.go"}, Order: DateOrder, Skip: 1})
+	require.NoError(t, err)
+	assert.Len(t, skipped, len(all)-1)
+
+	_, err = r.Log(context.TODO(), LogOpts{NoMerges: true, MergesOnly: true})
+	assert.Error(t, err)
+}
+
+func TestLogSignatures(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+
+	// commits signed with an unknown key
+	signature := "-----BEGIN PGP SIGNATURE-----\n\n" +
+		"iQGzBAABCgAdFiEEG/DbzpPGZoYcp+ZvX4wNuqmMz0kFAmrSpyYACgkQX4wNuqmM\n" +
+		"z0mauAv/Ve86IlVcrL4oYHy5Ed0R9jPx+T0qX5cYu2kMv7CfadYWn9Rm2l6+ngLU\n" +
+		"WqHqAwzrqrXbPCD5C2uVlJsBNM8QxXDxfwWZcHwKJtkJpWwBwd777AKAEc9OxSwz\n" +
+		"izoLAiKdBlRD18y9STHKn44wmoOTa9J+2U6sx42GS6KlVNx31e8O6anSau7E5xJa\n" +
+		"S9Dm0ljCbYMR9YrVdgvt2Xra/V+fCwE2EfXQuXa/powLyPv+KfQqzhV2ZTGZM7hQ\n" +
+		"bryw9AgdQhuFnFsR26lh6s515hXnRkZZZw5DdbmlfnM4HBQMRxIZQduJBmA7qcgQ\n" +
+		"asOstomFPtM5n9gslleWz8luaaRQJ2pGbuTUtVLJZMOVPhXuUTV0pgU/L2kb0bMz\n" +
+		"0uWzslmKYssqGfE0NrYDzcXxru4BaRSHp3z0ZZd4eTMAZqOSLRWpXL3+heFIBVus\n" +
+		"8Pa4BtxYOD7KSB8mL1dYxxf1e932dNOEhatHh6ZMinPnwK5UHaG+KTXbMiUb+GE3\n" +
+		"lb5vhPcj\n=v78x\n-----END PGP SIGNATURE-----"
+	parent := "HEAD"
+	for i := 0; i < 3; i++ {
+		tree, err := r.runCmd(ctx, "git", "rev-parse", parent+"^{tree}")
+		require.NoError(t, err)
+		parentHash, err := r.runCmd(ctx, "git", "rev-parse", parent)
+		require.NoError(t, err)
+		object := "tree " + strings.TrimSpace(tree) + "\nparent " + strings.TrimSpace(parentHash) + "\n" +
+			"author foo.bar <foo@bar.com> 1700000000 +0000\ncommitter foo.bar <foo@bar.com> 1700000000 +0000\n" +
+			"gpgsig " + strings.ReplaceAll(signature, "\n", "\n ") + "\n\nsigned " + strconv.Itoa(i) + "\n"
+		hash, err := r.runCmdWithStdin(ctx, strings.NewReader(object), "git", "hash-object", "-t", "commit", "-w", "--stdin")
+		require.NoError(t, err)
+		parent = strings.TrimSpace(hash)
+	}
+	_, err := r.runCmd(ctx, "git", "update-ref", "refs/heads/master", parent)
+	require.NoError(t, err)
+
+	commits, err := r.Log(ctx, LogOpts{CommitOption: CommitOption{DisableFiles: true, LoadTrustLevel: true}})
+	require.NoError(t, err)
+	require.Len(t, commits, 4)
+	for _, c := range commits[:3] {
+		assert.Equal(t, SignatureCannotCheck, c.Signature.Status, c.Subject)
+		assert.Equal(t, signature, c.Signature.Raw, c.Subject)
+		assert.Empty(t, c.Signature.TrustLevel, c.Subject)
+	}
+	assert.Equal(t, SignatureNone, commits[3].Signature.Status)
+	assert.Empty(t, commits[3].Signature.Raw)
+
+	// the iteration stops before reading all the signatures
+	it := r.LogIter(ctx, LogOpts{CommitOption: CommitOption{DisableFiles: true}})
+	require.True(t, it.Next())
+	assert.Equal(t, signature, it.Commit().Signature.Raw)
+	require.NoError(t, it.Close())
+	require.NoError(t, it.Err())
+}
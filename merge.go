@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MergeOpts is a optional struct for Merge
+type MergeOpts struct {
+	// FastForwardOnly refuses to merge if the current branch cannot be fast-forwarded (--ff-only)
+	FastForwardOnly bool
+	// NoFastForward creates a merge commit even if the current branch can be fast-forwarded (--no-ff)
+	NoFastForward bool
+	// Squash commits the changes of the merged branch as a single commit on the current branch, without a merge commit
+	Squash bool
+	// Strategy is the merge strategy (ie. ort, recursive, resolve, ours)
+	Strategy string
+	// StrategyOptions are the options of the merge strategy (ie. theirs, ignore-space-change)
+	StrategyOptions []string
+	// Message is the message of the commit. Default to the message prepared by git.
+	Message string
+}
+
+// MergeConflict is returned when changes do not apply because of conflicts. The repository is left with the conflicts to resolve, or to abort.
+type MergeConflict struct {
+	Files []ConflictFile
+	Err   error
+}
+
+func (e *MergeConflict) Error() string {
+	var paths []string
+	for _, f := range e.Files {
+		paths = append(paths, f.Path)
+	}
+	return fmt.Sprintf("conflicts on %s", strings.Join(paths, ", "))
+}
+
+func (e *MergeConflict) Unwrap() error {
+	return e.Err
+}
+
+// ConflictFile is a conflicted file. Its hashes are the blobs of the file in the common ancestor, in the current branch and in the merged changes,
+// a hash being empty if the file does not exist on this side.
+type ConflictFile struct {
+	Path       string
+	BaseHash   string
+	OursHash   string
+	TheirsHash string
+	// Types are the kinds of conflict on the file. They are only reported by CanMerge.
+	Types []ConflictType
+}
+
+// ConflictType is a kind of conflict, as reported by git merge-tree
+type ConflictType string
+
+const (
+	ConflictContents      ConflictType = "contents"
+	ConflictBinary        ConflictType = "binary"
+	ConflictModifyDelete  ConflictType = "modify/delete"
+	ConflictRenameDelete  ConflictType = "rename/delete"
+	ConflictRenameRename  ConflictType = "rename/rename"
+	ConflictFileDirectory ConflictType = "file/directory"
+	ConflictDistinctModes ConflictType = "distinct modes"
+)
+
+// Merge merges the ref in the current branch and returns the resulting commit.
+// If there are conflicts, a *MergeConflict is returned and the merge must be aborted with AbortMerge, or resolved and committed.
+func (r Repo) Merge(ctx context.Context, ref string, opts MergeOpts) (Commit, error) {
+	args := []string{"merge"}
+	if opts.FastForwardOnly {
+		args = append(args, "--ff-only")
+	}
+	if opts.NoFastForward {
+		args = append(args, "--no-ff")
+	}
+	if opts.Squash {
+		args = append(args, "--squash")
+	}
+	if opts.Strategy != "" {
+		args = append(args, "--strategy="+opts.Strategy)
+	}
+	for _, o := range opts.StrategyOptions {
+		args = append(args, "--strategy-option="+o)
+	}
+	if opts.Message != "" {
+		args = append(args, "-m", opts.Message)
+	} else {
+		args = append(args, "--no-edit")
+	}
+	args = append(args, ref)
+
+	if _, err := r.runCmd(ctx, "git", args...); err != nil {
+		return Commit{}, r.conflictError(ctx, err)
+	}
+
+	if opts.Squash {
+		// git merge --squash only updates the index
+		if _, err := r.runCmd(ctx, "git", "diff", "--cached", "--quiet"); err != nil {
+			commitArgs := []string{"commit", "--no-edit"}
+			if opts.Message != "" {
+				commitArgs = []string{"commit", "-m", opts.Message}
+			}
+			if _, err := r.runCmd(ctx, "git", commitArgs...); err != nil {
+				return Commit{}, fmt.Errorf("unable to commit the squashed changes: %w", err)
+			}
+		}
+	}
+	return r.GetCommit(ctx, "HEAD", CommitOption{DisableDiffDetail: true})
+}
+
+// AbortMerge aborts a merge stopped on conflicts and restores the state of the current branch before the merge
+func (r Repo) AbortMerge(ctx context.Context) error {
+	args := []string{"merge", "--abort"}
+	if _, err := r.runCmd(ctx, "git", "rev-parse", "--quiet", "--verify", "MERGE_HEAD"); err != nil {
+		// there is no merge in progress after a squash merge
+		args = []string{"reset", "--merge"}
+	}
+	if _, err := r.runCmd(ctx, "git", args...); err != nil {
+		return fmt.Errorf("unable to abort merge: %w", err)
+	}
+	return nil
+}
+
+// conflictError returns a *MergeConflict wrapping err if the index has conflicts, err otherwise
+func (r Repo) conflictError(ctx context.Context, err error) error {
+	files, lsErr := r.conflictFiles(ctx)
+	if lsErr != nil || len(files) == 0 {
+		return err
+	}
+	return &MergeConflict{Files: files, Err: err}
+}
+
+// conflictFiles returns the unmerged files of the index
+func (r Repo) conflictFiles(ctx context.Context) ([]ConflictFile, error) {
+	out, err := r.runCmd(ctx, "git", "ls-files", "--unmerged", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return parseConflictFiles(strings.Split(out, "\x00"))
+}
+
+// parseConflictFiles parses the unmerged entries of git ls-files --unmerged -z and git merge-tree -z
+func parseConflictFiles(entries []string) ([]ConflictFile, error) {
+	var files []ConflictFile
+	// <mode> <hash> <stage>\t<path>, the stages of a file being consecutive
+	for _, entry := range entries {
+		meta, path, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unable to parse unmerged entry %q", entry)
+		}
+		if len(files) == 0 || files[len(files)-1].Path != path {
+			files = append(files, ConflictFile{Path: path})
+		}
+		f := &files[len(files)-1]
+		switch fields[2] {
+		case "1":
+			f.BaseHash = fields[1]
+		case "2":
+			f.OursHash = fields[1]
+		case "3":
+			f.TheirsHash = fields[1]
+		}
+	}
+	return files, nil
+}
+
+// MergeCheck is the result of CanMerge
+type MergeCheck struct {
+	// Clean is set when the branches merge without conflict
+	Clean bool
+	// Tree is the hash of the tree of the merge. With conflicts, the conflicted files of the tree have conflict markers.
+	Tree      string
+	Conflicts []ConflictFile
+}
+
+// CanMerge merges head into base in memory, without modifying the index, the working tree or the refs. It requires git 2.38 or later.
+func (r Repo) CanMerge(ctx context.Context, base, head string) (MergeCheck, error) {
+	out, err := r.runCmd(ctx, "git", "merge-tree", "--write-tree", "-z", base, head)
+	if err != nil {
+		var gitErr *GitError
+		// merge-tree exits with 1 when there are conflicts
+		if !errors.As(err, &gitErr) || gitErr.ExitCode != 1 {
+			return MergeCheck{}, fmt.Errorf("unable to merge %s into %s: %w", head, base, err)
+		}
+		out = gitErr.Stdout
+	}
+	return parseMergeTree(out)
+}
+
+// parseMergeTree parses the git merge-tree --write-tree -z output
+func parseMergeTree(out string) (MergeCheck, error) {
+	// <tree>\0[<unmerged entries>\0\0<messages>]
+	records := strings.Split(out, "\x00")
+	check := MergeCheck{Tree: strings.TrimSpace(records[0])}
+	if check.Tree == "" {
+		return check, fmt.Errorf("unable to parse merge-tree output %q", out)
+	}
+	end := len(records)
+	for i := 1; i < len(records); i++ {
+		if records[i] == "" {
+			end = i
+			break
+		}
+	}
+	var err error
+	check.Conflicts, err = parseConflictFiles(records[1:end])
+	if err != nil {
+		return check, err
+	}
+	check.Clean = len(check.Conflicts) == 0
+
+	// <number of paths>\0<path>\0...<type>\0<message>\0
+	for i := end + 1; i < len(records) && records[i] != ""; {
+		n, err := strconv.Atoi(records[i])
+		if err != nil || i+n+2 >= len(records) {
+			return check, fmt.Errorf("unable to parse merge-tree message %q", records[i])
+		}
+		paths := records[i+1 : i+1+n]
+		kind := records[i+1+n]
+		i += n + 3
+		t, ok := strings.CutPrefix(kind, "CONFLICT (")
+		if !ok {
+			// informational message, ie. Auto-merging
+			continue
+		}
+		t = strings.TrimSuffix(t, ")")
+		for j := range check.Conflicts {
+			for _, p := range paths {
+				if check.Conflicts[j].Path == p {
+					check.Conflicts[j].Types = append(check.Conflicts[j].Types, ConflictType(t))
+					break
+				}
+			}
+		}
+	}
+	return check, nil
+}
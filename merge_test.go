@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n", "b": "b\n"})
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature", map[string]string{"c": "c\n"})
+	feature, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	require.NoError(t, r.Checkout(ctx, "master"))
+
+	c, err := r.Merge(ctx, "feature", MergeOpts{FastForwardOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, feature.LongHash, c.LongHash)
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "other"))
+	commitTestFiles(t, r, "other", map[string]string{"d": "d\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"e": "e\n"})
+
+	_, err = r.Merge(ctx, "other", MergeOpts{FastForwardOnly: true})
+	require.Error(t, err)
+	var conflict *MergeConflict
+	assert.False(t, errors.As(err, &conflict))
+
+	c, err = r.Merge(ctx, "other", MergeOpts{NoFastForward: true, Message: "merge other"})
+	require.NoError(t, err)
+	assert.Equal(t, "merge other", c.Subject)
+	assert.Len(t, c.Parents, 2)
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "squashed"))
+	commitTestFiles(t, r, "squashed 1", map[string]string{"f": "f\n"})
+	commitTestFiles(t, r, "squashed 2", map[string]string{"g": "g\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	c, err = r.Merge(ctx, "squashed", MergeOpts{Squash: true, Message: "squash"})
+	require.NoError(t, err)
+	assert.Equal(t, "squash", c.Subject)
+	assert.Len(t, c.Parents, 1)
+}
+
+func TestMergeConflict(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n", "b": "b\n"})
+	base, err := r.LatestCommit(ctx, CommitOption{DisableDiffDetail: true})
+	require.NoError(t, err)
+
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature", map[string]string{"a": "feature\n", "c": "feature\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n", "c": "master\n"})
+	master, err := r.LatestCommit(ctx, CommitOption{DisableDiffDetail: true})
+	require.NoError(t, err)
+
+	_, err = r.Merge(ctx, "feature", MergeOpts{})
+	var conflict *MergeConflict
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.Len(t, conflict.Files, 2)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+	assert.Equal(t, base.Files["a"].NewHash, conflict.Files[0].BaseHash)
+	assert.Equal(t, master.Files["a"].NewHash, conflict.Files[0].OursHash)
+	assert.NotEmpty(t, conflict.Files[0].TheirsHash)
+	assert.Equal(t, "c", conflict.Files[1].Path)
+	assert.Empty(t, conflict.Files[1].BaseHash)
+	assert.Equal(t, master.Files["c"].NewHash, conflict.Files[1].OursHash)
+	assert.EqualError(t, conflict, "conflicts on a, c")
+
+	require.NoError(t, r.AbortMerge(ctx))
+	s, err := r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, s.IsClean())
+
+	c, err := r.Merge(ctx, "feature", MergeOpts{StrategyOptions: []string{"theirs"}})
+	require.NoError(t, err)
+	assert.Len(t, c.Parents, 2)
+	content, err := r.runCmd(ctx, "git", "show", "HEAD:a")
+	require.NoError(t, err)
+	assert.Equal(t, "feature\n", content)
+
+	require.NoError(t, r.ResetHard(ctx, "HEAD~1"))
+	_, err = r.Merge(ctx, "feature", MergeOpts{Squash: true})
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.AbortMerge(ctx))
+	s, err = r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, s.IsClean())
+
+	// a squash merge does not write MERGE_HEAD
+	_, err = r.Merge(ctx, "feature", MergeOpts{Squash: true})
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	op, err := r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationMerge, op)
+	require.NoError(t, r.Abort(ctx))
+	s, err = r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, s.IsClean())
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+
+	_, err = r.Merge(ctx, "feature", MergeOpts{Squash: true})
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.Write("a", strings.NewReader("resolved\n")))
+	require.NoError(t, r.Write("c", strings.NewReader("resolved\n")))
+	require.NoError(t, r.Add(ctx, "a", "c"))
+	require.NoError(t, r.Continue(ctx))
+	head, err := r.LatestCommit(ctx, CommitOption{DisableDiffDetail: true})
+	require.NoError(t, err)
+	require.Len(t, head.Parents, 1)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+}
+
+func TestCanMerge(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n", "d": "d\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature", map[string]string{"a": "feature\n", "b": "b\n"})
+	require.NoError(t, r.Remove(ctx, "d"))
+	require.NoError(t, r.Commit(ctx, "remove d"))
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n", "d": "master\n"})
+	head, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+
+	check, err := r.CanMerge(ctx, "master", "feature~1")
+	require.NoError(t, err)
+	assert.False(t, check.Clean)
+	require.Len(t, check.Conflicts, 1)
+	assert.Equal(t, "a", check.Conflicts[0].Path)
+	assert.Equal(t, []ConflictType{ConflictContents}, check.Conflicts[0].Types)
+	assert.NotEmpty(t, check.Conflicts[0].BaseHash)
+	assert.NotEmpty(t, check.Conflicts[0].OursHash)
+	assert.NotEmpty(t, check.Conflicts[0].TheirsHash)
+
+	check, err = r.CanMerge(ctx, "master", "feature")
+	require.NoError(t, err)
+	require.Len(t, check.Conflicts, 2)
+	assert.Equal(t, "d", check.Conflicts[1].Path)
+	assert.Equal(t, []ConflictType{ConflictModifyDelete}, check.Conflicts[1].Types)
+	assert.Empty(t, check.Conflicts[1].TheirsHash)
+
+	// nothing has been modified
+	s, err := r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, s.IsClean())
+	assert.Equal(t, head.LongHash, s.Head)
+
+	path := t.TempDir()
+	out, err := exec.Command("git", "clone", "--quiet", "--bare", r.path, path).CombinedOutput()
+	require.NoError(t, err, string(out))
+	bare, err := NewBare(ctx, path)
+	require.NoError(t, err)
+	check, err = bare.CanMerge(ctx, "feature", "feature~1")
+	require.NoError(t, err)
+	assert.True(t, check.Clean)
+	assert.Empty(t, check.Conflicts)
+	tree, err := r.runCmd(ctx, "git", "rev-parse", "feature^{tree}")
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(tree), check.Tree)
+
+	_, err = r.CanMerge(ctx, "master", "unknown")
+	assert.Error(t, err)
+}
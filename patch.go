@@ -0,0 +1,311 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Patch is a patch of a mailbox, as produced by git format-patch
+type Patch struct {
+	// Commit is the hash of the exported commit
+	Commit      string
+	Subject     string
+	Author      string
+	AuthorEmail string
+	Date        time.Time
+	// Content is the mbox content of the patch
+	Content string
+}
+
+// ApplyOpts is a optional struct for ApplyPatch
+type ApplyOpts struct {
+	// Index applies the patch to the index and to the working tree
+	Index bool
+	// Cached applies the patch to the index only
+	Cached bool
+	// Check only checks that the patch applies
+	Check bool
+	// ThreeWay falls back on a three-way merge when the patch does not apply cleanly
+	ThreeWay bool
+	Reverse  bool
+}
+
+// MailboxOpts is a optional struct for ApplyMailbox
+type MailboxOpts struct {
+	// ThreeWay falls back on a three-way merge when a patch does not apply cleanly
+	ThreeWay bool
+	// Signoff adds a Signed-off-by trailer to the commits
+	Signoff bool
+	// SkipFailed skips the patches which do not apply and applies the next ones. By default, the whole mailbox is aborted on the first failure.
+	SkipFailed bool
+}
+
+// PatchError is returned by ApplyPatch and ApplyMailbox when patches do not apply
+type PatchError struct {
+	Failures []PatchFailure
+	Err      error
+}
+
+func (e *PatchError) Error() string {
+	var failures []string
+	for _, f := range e.Failures {
+		failures = append(failures, f.String())
+	}
+	return fmt.Sprintf("unable to apply patch: %s", strings.Join(failures, ", "))
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// PatchFailure describes a file, or a hunk, which does not apply
+type PatchFailure struct {
+	// Patch is the number of the patch in the mailbox, from 1. It is 0 for ApplyPatch.
+	Patch   int
+	Subject string
+	// Filename is empty if the whole patch is rejected
+	Filename string
+	// Line is the line of the original file where the hunk does not apply, 0 if the whole file is rejected
+	Line int
+	// Hunk is the hunk which does not apply, if any
+	Hunk   *Hunk
+	Reason string
+}
+
+func (f PatchFailure) String() string {
+	var s string
+	if f.Patch > 0 {
+		s = fmt.Sprintf("patch %d (%s): ", f.Patch, f.Subject)
+	}
+	if f.Filename != "" {
+		s += f.Filename
+		if f.Line > 0 {
+			s += ":" + strconv.Itoa(f.Line)
+		}
+		s += ": "
+	}
+	return s + f.Reason
+}
+
+var (
+	mailboxFromRegexp     = regexp.MustCompile(`(?m)^From ([0-9a-f]{40,64}) Mon Sep 17 00:00:00 2001$`)
+	patchSubjectRegexp    = regexp.MustCompile(`^\[PATCH[^\]]*\]\s*`)
+	patchFailedRegexp     = regexp.MustCompile(`^error: patch failed: (.+):(\d+)$`)
+	patchFileErrorRegexp  = regexp.MustCompile(`^error: (.+?): (.+)$`)
+	patchConflictRegexp   = regexp.MustCompile(`^Applied patch to '(.+)' with conflicts\.$`)
+	mailboxFailedAtRegexp = regexp.MustCompile(`(?m)^Patch failed at (\d+) (.*)$`)
+)
+
+// FormatPatch exports the commits of the range (ie. "v1.0.0..HEAD") as mbox patches, from the oldest
+func (r Repo) FormatPatch(ctx context.Context, rangeSpec string) ([]Patch, error) {
+	// the patches are not read with runCmd which rewrites the CR
+	out := new(bytes.Buffer)
+	if err := r.execCmd(ctx, Command{Name: "git", Args: []string{"format-patch", "--stdout", "--no-color", rangeSpec}, Stdout: out}); err != nil {
+		return nil, err
+	}
+	return splitMailbox(out.String())
+}
+
+// splitMailbox splits a mailbox produced by git format-patch in patches
+func splitMailbox(mbox string) ([]Patch, error) {
+	var patches []Patch
+	locs := mailboxFromRegexp.FindAllStringSubmatchIndex(mbox, -1)
+	for i, loc := range locs {
+		end := len(mbox)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		p := Patch{
+			Commit:  mbox[loc[2]:loc[3]],
+			Content: mbox[loc[0]:end],
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(mbox[loc[1]+1 : end]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse patch %s: %w", p.Commit, err)
+		}
+		if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+			p.Author, p.AuthorEmail = from[0].Name, from[0].Address
+		}
+		p.Date, _ = msg.Header.Date()
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			subject = msg.Header.Get("Subject")
+		}
+		p.Subject = patchSubjectRegexp.ReplaceAllString(subject, "")
+		patches = append(patches, p)
+	}
+	return patches, nil
+}
+
+// ApplyPatch applies a patch to the working tree, to the index, or checks that it applies
+func (r Repo) ApplyPatch(ctx context.Context, patch io.Reader, opts ApplyOpts) error {
+	content, err := io.ReadAll(patch)
+	if err != nil {
+		return err
+	}
+	args := []string{"apply"}
+	if opts.Index {
+		args = append(args, "--index")
+	}
+	if opts.Cached {
+		args = append(args, "--cached")
+	}
+	if opts.Check {
+		args = append(args, "--check")
+	}
+	if opts.ThreeWay {
+		args = append(args, "--3way")
+	}
+	if opts.Reverse {
+		args = append(args, "--reverse")
+	}
+	args = append(args, "-")
+	if _, err := r.runCmdWithStdin(ctx, bytes.NewReader(content), "git", args...); err != nil {
+		return newPatchError(err, 0, "", string(content))
+	}
+	return nil
+}
+
+// ApplyMailbox applies the patches of a mailbox as commits, preserving their authorship. The new commits are returned from the oldest.
+// If a patch does not apply, a *PatchError is returned.
+func (r Repo) ApplyMailbox(ctx context.Context, mbox io.Reader, opts MailboxOpts) ([]Commit, error) {
+	content, err := io.ReadAll(mbox)
+	if err != nil {
+		return nil, err
+	}
+	patches, err := splitMailbox(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := r.runCmd(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		// unborn branch
+		head = ""
+	}
+	head = strings.TrimSpace(head)
+
+	args := []string{"am"}
+	if opts.ThreeWay {
+		args = append(args, "--3way")
+	}
+	if opts.Signoff {
+		args = append(args, "--signoff")
+	}
+	_, err = r.runCmdWithStdin(ctx, bytes.NewReader(content), "git", args...)
+
+	var patchErr *PatchError
+	for i := 0; err != nil && i <= len(patches); i++ {
+		var gitErr *GitError
+		if !errors.As(err, &gitErr) {
+			return nil, err
+		}
+		m := mailboxFailedAtRegexp.FindStringSubmatch(gitErr.Stdout + "\n" + gitErr.Stderr)
+		if m == nil {
+			// git am has not started
+			return nil, err
+		}
+		n, _ := strconv.Atoi(m[1])
+		var patchContent string
+		if n > 0 && n <= len(patches) {
+			patchContent = patches[n-1].Content
+		}
+		failure := newPatchError(err, n, m[2], patchContent)
+		if patchErr == nil {
+			patchErr = failure
+		} else {
+			patchErr.Failures = append(patchErr.Failures, failure.Failures...)
+		}
+		if !opts.SkipFailed {
+			if _, abortErr := r.runCmd(ctx, "git", "am", "--abort"); abortErr != nil {
+				return nil, abortErr
+			}
+			return nil, patchErr
+		}
+		_, err = r.runCmd(ctx, "git", "-c", "core.editor=true", "am", "--skip")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rev := "HEAD"
+	if head != "" {
+		rev = head + "..HEAD"
+	}
+	commits, err := r.Log(ctx, LogOpts{Revisions: []string{rev}, Reverse: true, CommitOption: CommitOption{DisableDiffDetail: true}})
+	if err != nil {
+		return nil, err
+	}
+	if patchErr != nil {
+		return commits, patchErr
+	}
+	return commits, nil
+}
+
+// newPatchError parses the errors of git apply and git am. The failing hunks are looked up in the patch.
+func newPatchError(err error, patch int, subject, content string) *PatchError {
+	e := &PatchError{Err: err}
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		e.Failures = []PatchFailure{{Patch: patch, Subject: subject, Reason: err.Error()}}
+		return e
+	}
+
+	for _, l := range strings.Split(gitErr.Stderr+"\n"+gitErr.Stdout, "\n") {
+		l = strings.TrimSpace(l)
+		var last *PatchFailure
+		if len(e.Failures) > 0 {
+			last = &e.Failures[len(e.Failures)-1]
+		}
+		if m := patchFailedRegexp.FindStringSubmatch(l); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			e.Failures = append(e.Failures, PatchFailure{Filename: m[1], Line: line})
+		} else if m := patchConflictRegexp.FindStringSubmatch(l); m != nil {
+			e.Failures = append(e.Failures, PatchFailure{Filename: m[1], Reason: "conflicts"})
+		} else if m := patchFileErrorRegexp.FindStringSubmatch(l); m != nil {
+			if last != nil && last.Filename == m[1] && last.Reason == "" {
+				last.Reason = m[2]
+			} else {
+				e.Failures = append(e.Failures, PatchFailure{Filename: m[1], Reason: m[2]})
+			}
+		} else if strings.HasPrefix(l, "error: ") {
+			e.Failures = append(e.Failures, PatchFailure{Reason: strings.TrimPrefix(l, "error: ")})
+		}
+	}
+	if len(e.Failures) == 0 {
+		e.Failures = []PatchFailure{{Reason: strings.TrimSpace(gitErr.Stderr)}}
+	}
+
+	patches := splitPatch(content)
+	for i := range e.Failures {
+		f := &e.Failures[i]
+		f.Patch, f.Subject = patch, subject
+		if f.Reason == "" {
+			f.Reason = "patch does not apply"
+		}
+		if f.Line == 0 {
+			continue
+		}
+		detail, err := parseDiffDetail(patches[f.Filename])
+		if err != nil {
+			continue
+		}
+		for _, h := range detail.Hunks {
+			if h.OldStart == f.Line {
+				h := h
+				f.Hunk = &h
+				break
+			}
+		}
+	}
+	return e
+}
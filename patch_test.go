@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitMailbox(t *testing.T) {
+	mbox := "From 4a9922c6da12d70b58a7e782bb3d81650601600c Mon Sep 17 00:00:00 2001\n" +
+		"From: =?UTF-8?q?Fran=C3=A7ois=20Samin?= <foo@bar.com>\n" +
+		"Date: Thu, 1 Jun 2023 12:00:00 +0200\n" +
+		"Subject: [PATCH 1/2] add a very long subject which is folded by git\n" +
+		" format-patch\n" +
+		"\n" +
+		"---\n" +
+		" f | 1 +\n" +
+		"\n" +
+		"diff --git a/f b/f\n" +
+		"From 821aeaa48e33373a1b61644b31a7b240092262a4 Mon Sep 17 00:00:00 2001\n" +
+		"From: foo.bar <foo@bar.com>\n" +
+		"Date: Thu, 1 Jun 2023 13:00:00 +0200\n" +
+		"Subject: [PATCH 2/2] second\n" +
+		"\n" +
+		"body\n"
+
+	patches, err := splitMailbox(mbox)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+	assert.Equal(t, "4a9922c6da12d70b58a7e782bb3d81650601600c", patches[0].Commit)
+	assert.Equal(t, "François Samin", patches[0].Author)
+	assert.Equal(t, "foo@bar.com", patches[0].AuthorEmail)
+	assert.Equal(t, "add a very long subject which is folded by git format-patch", patches[0].Subject)
+	assert.Equal(t, int64(1685613600), patches[0].Date.Unix())
+	assert.True(t, strings.HasSuffix(patches[0].Content, "diff --git a/f b/f\n"))
+	assert.Equal(t, "second", patches[1].Subject)
+	assert.True(t, strings.HasSuffix(patches[1].Content, "body\n"))
+}
+
+func Test_newPatchError(t *testing.T) {
+	patch := "diff --git a/f b/f\n" +
+		"--- a/f\n" +
+		"+++ b/f\n" +
+		"@@ -1 +1 @@\n" +
+		"-a\n" +
+		"+b\n" +
+		"@@ -10,2 +10,2 @@\n" +
+		"-c\n" +
+		"+d\n" +
+		" e\n"
+	err := newPatchError(&GitError{Stderr: "error: patch failed: f:10\nerror: f: patch does not apply\nerror: g: does not exist in index\n"}, 2, "second", patch)
+	require.Len(t, err.Failures, 2)
+	assert.Equal(t, PatchFailure{Patch: 2, Subject: "second", Filename: "f", Line: 10, Hunk: err.Failures[0].Hunk, Reason: "patch does not apply"}, err.Failures[0])
+	require.NotNil(t, err.Failures[0].Hunk)
+	assert.Equal(t, []string{"d"}, err.Failures[0].Hunk.AddedLines)
+	assert.Equal(t, PatchFailure{Patch: 2, Subject: "second", Filename: "g", Reason: "does not exist in index"}, err.Failures[1])
+	assert.Equal(t, "unable to apply patch: patch 2 (second): f:10: patch does not apply, patch 2 (second): g: does not exist in index", err.Error())
+}
+
+func TestFormatPatchAndApply(t *testing.T) {
+	src := initTestRepo(t, map[string]string{"f": "a\nb\nc\n"})
+	commitTestFiles(t, src, "second", map[string]string{"f": "a\nB\nc\n"})
+	commitTestFiles(t, src, "third", map[string]string{"g": "g\n"})
+
+	patches, err := src.FormatPatch(context.TODO(), "HEAD~2..HEAD")
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+	assert.Equal(t, "foo.bar", patches[0].Author)
+	assert.Contains(t, patches[0].Subject, "second")
+
+	// apply the first patch to the working tree of another repository
+	dst := initTestRepo(t, map[string]string{"f": "a\nb\nc\n"})
+	require.NoError(t, dst.ApplyPatch(context.TODO(), strings.NewReader(patches[0].Content), ApplyOpts{Check: true}))
+	require.NoError(t, dst.ApplyPatch(context.TODO(), strings.NewReader(patches[0].Content), ApplyOpts{}))
+	err = dst.ApplyPatch(context.TODO(), strings.NewReader(patches[0].Content), ApplyOpts{Check: true})
+	var patchErr *PatchError
+	require.True(t, errors.As(err, &patchErr), "%v", err)
+	require.NotEmpty(t, patchErr.Failures)
+	assert.Equal(t, "f", patchErr.Failures[0].Filename)
+	require.NotNil(t, patchErr.Failures[0].Hunk)
+	assert.Equal(t, []string{"B"}, patchErr.Failures[0].Hunk.AddedLines)
+	require.NoError(t, dst.ResetHard(context.TODO(), "HEAD"))
+
+	// apply the mailbox as commits
+	var mbox strings.Builder
+	for _, p := range patches {
+		mbox.WriteString(p.Content)
+	}
+	commits, err := dst.ApplyMailbox(context.TODO(), strings.NewReader(mbox.String()), MailboxOpts{})
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Equal(t, patches[0].Subject, commits[0].Subject)
+	assert.Equal(t, "foo.bar", commits[0].Author)
+	assert.Contains(t, commits[1].Files, "g")
+
+	// the first patch does not apply anymore
+	_, err = dst.ApplyMailbox(context.TODO(), strings.NewReader(mbox.String()), MailboxOpts{})
+	require.True(t, errors.As(err, &patchErr), "%v", err)
+	require.NotEmpty(t, patchErr.Failures)
+	assert.Equal(t, 1, patchErr.Failures[0].Patch)
+	assert.Equal(t, "f", patchErr.Failures[0].Filename)
+	head, err := dst.LatestCommit(context.TODO(), CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	assert.Equal(t, commits[1].LongHash, head.LongHash, "the mailbox must be aborted")
+
+	// the failing patches are skipped
+	commits, err = dst.ApplyMailbox(context.TODO(), strings.NewReader(mbox.String()), MailboxOpts{SkipFailed: true})
+	require.True(t, errors.As(err, &patchErr), "%v", err)
+	assert.Len(t, patchErr.Failures, 2)
+	assert.Empty(t, commits)
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RebaseOpts is a optional struct for Rebase
+type RebaseOpts struct {
+	// Onto rebases the commits on this ref instead of the upstream (--onto)
+	Onto string
+	// Autosquash moves the fixup! and squash! commits after the commits they fix, and squashes them
+	Autosquash bool
+	// RebaseMerges recreates the merge commits instead of flattening the history (--rebase-merges)
+	RebaseMerges bool
+	// Autostash stashes the local changes before the rebase and applies them after
+	Autostash bool
+}
+
+// Operation is a git operation which may be stopped on conflicts
+type Operation string
+
+const (
+	OperationNone       Operation = ""
+	OperationMerge      Operation = "merge"
+	OperationRebase     Operation = "rebase"
+	OperationCherryPick Operation = "cherry-pick"
+	OperationRevert     Operation = "revert"
+	OperationMailbox    Operation = "am"
+)
+
+// Rebase rebases the current branch on the upstream, or on the configured upstream of the branch if upstream is empty, and returns the new head.
+// If there are conflicts, a *MergeConflict is returned and the rebase must be continued, skipped or aborted.
+// If the autostash does not apply on the new head, a *MergeConflict is returned too, the rebase being done and the local changes being kept in the stash.
+func (r Repo) Rebase(ctx context.Context, upstream string, opts RebaseOpts) (Commit, error) {
+	args := []string{"-c", "core.editor=true", "-c", "sequence.editor=true", "rebase"}
+	if opts.Onto != "" {
+		args = append(args, "--onto", opts.Onto)
+	}
+	if opts.Autosquash {
+		// git applies the autosquash in interactive mode only, the todo list is accepted as is by the sequence editor
+		args = append(args, "--interactive", "--autosquash")
+	}
+	if opts.RebaseMerges {
+		args = append(args, "--rebase-merges")
+	}
+	if opts.Autostash {
+		args = append(args, "--autostash")
+	}
+	if upstream != "" {
+		args = append(args, upstream)
+	}
+	stash := r.stashHead(ctx)
+	if _, err := r.runCmd(ctx, "git", args...); err != nil {
+		return Commit{}, r.conflictError(ctx, err)
+	}
+	if err := r.autostashError(ctx, stash); err != nil {
+		return Commit{}, err
+	}
+	return r.GetCommit(ctx, "HEAD", CommitOption{DisableDiffDetail: true})
+}
+
+// stashHead returns the last stash entry, empty if there is none
+func (r Repo) stashHead(ctx context.Context) string {
+	out, err := r.runCmd(ctx, "git", "rev-parse", "--quiet", "--verify", "refs/stash")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// autostashError returns a *MergeConflict if the autostash of a rebase did not apply, given the last stash entry before the rebase.
+// git exits with 0 and keeps the local changes in a new stash entry.
+func (r Repo) autostashError(ctx context.Context, stashBefore string) error {
+	if r.stashHead(ctx) == stashBefore {
+		return nil
+	}
+	return r.conflictError(ctx, errors.New("applying the autostash resulted in conflicts, the local changes are kept in the stash"))
+}
+
+// CherryPick applies the changes of the commits on the current branch and returns the new commits, from the oldest.
+// If there are conflicts, a *MergeConflict is returned and the cherry-pick must be continued, skipped or aborted.
+func (r Repo) CherryPick(ctx context.Context, commits ...string) ([]Commit, error) {
+	return r.applyCommits(ctx, "cherry-pick", commits)
+}
+
+// Revert reverts the changes of the commits on the current branch and returns the new commits, from the oldest.
+// If there are conflicts, a *MergeConflict is returned and the revert must be continued, skipped or aborted.
+func (r Repo) Revert(ctx context.Context, commits ...string) ([]Commit, error) {
+	return r.applyCommits(ctx, "revert", commits)
+}
+
+func (r Repo) applyCommits(ctx context.Context, command string, commits []string) ([]Commit, error) {
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commit to %s", command)
+	}
+	head, err := r.runCmd(ctx, "git", "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{command, "--no-edit"}, commits...)
+	if _, err := r.runCmd(ctx, "git", args...); err != nil {
+		return nil, r.conflictError(ctx, err)
+	}
+	return r.Log(ctx, LogOpts{Revisions: []string{strings.TrimSpace(head) + "..HEAD"}, Reverse: true, CommitOption: CommitOption{DisableDiffDetail: true}})
+}
+
+// InProgressOperation returns the merge, rebase, cherry-pick, revert or mailbox application stopped on conflicts, if any.
+// The state refs are checked with git, the state files of the git directory are read on the local filesystem.
+func (r Repo) InProgressOperation(ctx context.Context) (Operation, error) {
+	out, err := r.runCmd(ctx, "git", "rev-parse",
+		"--git-path", "rebase-merge",
+		"--git-path", "rebase-apply",
+		"--git-path", "rebase-apply/applying",
+		"--git-path", "SQUASH_MSG",
+		"--git-path", "sequencer/todo",
+	)
+	if err != nil {
+		return OperationNone, err
+	}
+	paths := strings.Split(strings.TrimSpace(out), "\n")
+	path := func(i int) string {
+		if i >= len(paths) || paths[i] == "" {
+			return ""
+		}
+		if filepath.IsAbs(paths[i]) {
+			return paths[i]
+		}
+		return filepath.Join(r.path, paths[i])
+	}
+	exists := func(i int) bool {
+		p := path(i)
+		if p == "" {
+			return false
+		}
+		_, err := os.Stat(p)
+		return err == nil
+	}
+
+	switch {
+	case exists(2):
+		return OperationMailbox, nil
+	case exists(0), exists(1), r.hasRef(ctx, "REBASE_HEAD"):
+		return OperationRebase, nil
+	case r.hasRef(ctx, "MERGE_HEAD"):
+		return OperationMerge, nil
+	case r.hasRef(ctx, "CHERRY_PICK_HEAD"):
+		return OperationCherryPick, nil
+	case r.hasRef(ctx, "REVERT_HEAD"):
+		return OperationRevert, nil
+	case exists(3):
+		// a squash merge does not write MERGE_HEAD, its message is removed once committed or reset
+		return OperationMerge, nil
+	}
+	// the conflicts of a cherry-pick or a revert of several commits have been committed, the next commits remain
+	todo, err := os.ReadFile(path(4))
+	if err != nil {
+		return OperationNone, nil
+	}
+	if strings.HasPrefix(string(todo), "revert") {
+		return OperationRevert, nil
+	}
+	return OperationCherryPick, nil
+}
+
+// hasRef returns true if the ref exists (ie. MERGE_HEAD)
+func (r Repo) hasRef(ctx context.Context, ref string) bool {
+	_, err := r.runCmd(ctx, "git", "rev-parse", "--quiet", "--verify", ref)
+	return err == nil
+}
+
+// Continue continues the operation stopped on conflicts, once they are resolved and added to the index.
+// If the operation stops on new conflicts, a *MergeConflict is returned.
+func (r Repo) Continue(ctx context.Context) error {
+	return r.sequenceOperation(ctx, "--continue")
+}
+
+// Skip skips the commit, or the patch, of the rebase, cherry-pick, revert or mailbox application stopped on conflicts.
+// If the operation stops on new conflicts, a *MergeConflict is returned.
+func (r Repo) Skip(ctx context.Context) error {
+	return r.sequenceOperation(ctx, "--skip")
+}
+
+// Abort aborts the operation stopped on conflicts and restores the state of the current branch before the operation
+func (r Repo) Abort(ctx context.Context) error {
+	op, err := r.InProgressOperation(ctx)
+	if err != nil {
+		return err
+	}
+	if op == OperationMerge {
+		return r.AbortMerge(ctx)
+	}
+	return r.sequenceOperation(ctx, "--abort")
+}
+
+func (r Repo) sequenceOperation(ctx context.Context, action string) error {
+	op, err := r.InProgressOperation(ctx)
+	if err != nil {
+		return err
+	}
+	if op == OperationNone {
+		return fmt.Errorf("unable to %s: no operation in progress", strings.TrimPrefix(action, "--"))
+	}
+	if op == OperationMerge && action == "--skip" {
+		return fmt.Errorf("unable to skip: a merge cannot be skipped")
+	}
+	args := []string{"-c", "core.editor=true", string(op), action}
+	if op == OperationMerge && action == "--continue" {
+		// git merge --continue requires MERGE_HEAD, which is not written by a squash merge
+		args = []string{"commit", "--no-edit"}
+	}
+	stash := r.stashHead(ctx)
+	if _, err := r.runCmd(ctx, "git", args...); err != nil {
+		if action == "--abort" {
+			return fmt.Errorf("unable to abort %s: %w", op, err)
+		}
+		return r.conflictError(ctx, err)
+	}
+	if op == OperationRebase {
+		// the autostash is applied once the rebase is done
+		return r.autostashError(ctx, stash)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebase(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	require.NoError(t, r.Write("b", strings.NewReader("b\n")))
+	require.NoError(t, r.Add(ctx, "b"))
+	_, err := r.runCmd(ctx, "git", "commit", "-m", "feature")
+	require.NoError(t, err)
+	require.NoError(t, r.Write("b", strings.NewReader("fixed\n")))
+	require.NoError(t, r.Add(ctx, "b"))
+	_, err = r.runCmd(ctx, "git", "commit", "-m", "fixup! feature")
+	require.NoError(t, err)
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"c": "c\n"})
+	master, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	require.NoError(t, r.Checkout(ctx, "feature"))
+	require.NoError(t, r.Write("a", strings.NewReader("local\n")))
+
+	c, err := r.Rebase(ctx, "master", RebaseOpts{Autosquash: true, Autostash: true})
+	require.NoError(t, err)
+	assert.Equal(t, "feature", c.Subject)
+	assert.Equal(t, []string{master.LongHash}, c.Parents)
+	content, err := r.runCmd(ctx, "git", "show", "HEAD:b")
+	require.NoError(t, err)
+	assert.Equal(t, "fixed\n", content)
+	s, err := r.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []StatusFile{{Path: "a", Unstaged: FileModified}}, s.Files)
+}
+
+func TestRebaseConflict(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature 1", map[string]string{"a": "feature\n"})
+	commitTestFiles(t, r, "feature 2", map[string]string{"b": "b\n"})
+	feature, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n"})
+	require.NoError(t, r.Checkout(ctx, "feature"))
+
+	op, err := r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	assert.Error(t, r.Continue(ctx))
+
+	_, err = r.Rebase(ctx, "master", RebaseOpts{})
+	var conflict *MergeConflict
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.Len(t, conflict.Files, 1)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationRebase, op)
+
+	require.NoError(t, r.Abort(ctx))
+	head, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	assert.Equal(t, feature.LongHash, head.LongHash)
+
+	_, err = r.Rebase(ctx, "master", RebaseOpts{})
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.Skip(ctx))
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	commits, err := r.Log(ctx, LogOpts{Revisions: []string{"master..HEAD"}, CommitOption: CommitOption{DisableFiles: true}})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Contains(t, commits[0].Subject, "feature 2")
+
+	require.NoError(t, r.ResetHard(ctx, feature.LongHash))
+	_, err = r.Rebase(ctx, "master", RebaseOpts{})
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.Write("a", strings.NewReader("resolved\n")))
+	require.NoError(t, r.Add(ctx, "a"))
+	require.NoError(t, r.Continue(ctx))
+	commits, err = r.Log(ctx, LogOpts{Revisions: []string{"master..HEAD"}, CommitOption: CommitOption{DisableFiles: true}})
+	require.NoError(t, err)
+	assert.Len(t, commits, 2)
+}
+
+func TestRebaseAutostashConflict(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature", map[string]string{"b": "b\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n"})
+	require.NoError(t, r.Checkout(ctx, "feature"))
+
+	// the local change conflicts with the change of master
+	require.NoError(t, r.Write("a", strings.NewReader("local\n")))
+	_, err := r.Rebase(ctx, "master", RebaseOpts{Autostash: true})
+	var conflict *MergeConflict
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.Len(t, conflict.Files, 1)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+
+	// the rebase is done and the local change is kept in the stash
+	op, err := r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	commits, err := r.Log(ctx, LogOpts{Revisions: []string{"master..HEAD"}, CommitOption: CommitOption{DisableFiles: true}})
+	require.NoError(t, err)
+	assert.Len(t, commits, 1)
+	out, err := r.runCmd(ctx, "git", "stash", "show", "--patch")
+	require.NoError(t, err)
+	assert.Contains(t, out, "+local")
+
+	// a local change which applies does not return an error
+	require.NoError(t, r.ResetHard(ctx, "HEAD"))
+	_, err = r.runCmd(ctx, "git", "stash", "drop")
+	require.NoError(t, err)
+	require.NoError(t, r.Write("c", strings.NewReader("c\n")))
+	require.NoError(t, r.Add(ctx, "c"))
+	_, err = r.Rebase(ctx, "master~1", RebaseOpts{Autostash: true})
+	require.NoError(t, err)
+	s, err := r.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, s.Files, 1)
+	assert.Equal(t, "c", s.Files[0].Path)
+}
+
+func TestCherryPickAndRevert(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature 1", map[string]string{"b": "b\n"})
+	commitTestFiles(t, r, "feature 2", map[string]string{"a": "feature\n"})
+	commitTestFiles(t, r, "feature 3", map[string]string{"c": "c\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+
+	commits, err := r.CherryPick(ctx, "feature~2", "feature")
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Contains(t, commits[0].Subject, "feature 1")
+	assert.Contains(t, commits[1].Subject, "feature 3")
+
+	commits, err = r.Revert(ctx, "HEAD")
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Contains(t, commits[0].Subject, "Revert")
+
+	_, err = r.CherryPick(ctx)
+	assert.Error(t, err)
+
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n"})
+	_, err = r.CherryPick(ctx, "feature~1")
+	var conflict *MergeConflict
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+	op, err := r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCherryPick, op)
+	assert.Error(t, r.Continue(ctx))
+	require.NoError(t, r.Abort(ctx))
+	s, err := r.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, s.IsClean())
+
+	_, err = r.Revert(ctx, "feature~2")
+	require.NoError(t, err)
+	_, err = r.Revert(ctx, "feature~1")
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationRevert, op)
+	require.NoError(t, r.Abort(ctx))
+}
+
+func TestCherryPickAndRevertSequence(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+	require.NoError(t, r.CheckoutNewBranch(ctx, "feature"))
+	commitTestFiles(t, r, "feature 1", map[string]string{"b": "b\n"})
+	commitTestFiles(t, r, "feature 2", map[string]string{"a": "feature\n"})
+	commitTestFiles(t, r, "feature 3", map[string]string{"c": "c\n"})
+	require.NoError(t, r.Checkout(ctx, "master"))
+	commitTestFiles(t, r, "master", map[string]string{"a": "master\n"})
+	master, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+
+	subjects := func(revision string) []string {
+		commits, err := r.Log(ctx, LogOpts{Revisions: []string{revision}, Reverse: true, CommitOption: CommitOption{DisableFiles: true}})
+		require.NoError(t, err)
+		var subjects []string
+		for _, c := range commits {
+			subjects = append(subjects, strings.Trim(c.Subject, `"`))
+		}
+		return subjects
+	}
+
+	// the cherry-pick stops on the second commit
+	_, err = r.CherryPick(ctx, "feature~3..feature")
+	var conflict *MergeConflict
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+	op, err := r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCherryPick, op)
+
+	// once the conflicts are committed, the remaining commits are detected from the sequencer
+	require.NoError(t, r.Write("a", strings.NewReader("resolved\n")))
+	require.NoError(t, r.Add(ctx, "a"))
+	_, err = r.runCmd(ctx, "git", "commit", "--no-edit")
+	require.NoError(t, err)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCherryPick, op)
+	require.NoError(t, r.Continue(ctx))
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	assert.Equal(t, []string{"feature 1", "feature 2", "feature 3"}, subjects(master.LongHash+".."))
+
+	require.NoError(t, r.ResetHard(ctx, master.LongHash))
+	_, err = r.CherryPick(ctx, "feature~3..feature")
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.Skip(ctx))
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	assert.Equal(t, []string{"feature 1", "feature 3"}, subjects(master.LongHash+".."))
+
+	// the revert stops on the second commit
+	require.NoError(t, r.Checkout(ctx, "feature"))
+	commitTestFiles(t, r, "other", map[string]string{"a": "other\n"})
+	other, err := r.LatestCommit(ctx, CommitOption{DisableFiles: true})
+	require.NoError(t, err)
+	_, err = r.Revert(ctx, "HEAD~1", "HEAD~2", "HEAD~3")
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	assert.Equal(t, "a", conflict.Files[0].Path)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationRevert, op)
+
+	require.NoError(t, r.Write("a", strings.NewReader("resolved\n")))
+	require.NoError(t, r.Add(ctx, "a"))
+	_, err = r.runCmd(ctx, "git", "commit", "--no-edit")
+	require.NoError(t, err)
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationRevert, op)
+	require.NoError(t, r.Continue(ctx))
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	assert.Len(t, subjects(other.LongHash+".."), 3)
+
+	require.NoError(t, r.ResetHard(ctx, other.LongHash))
+	_, err = r.Revert(ctx, "HEAD~1", "HEAD~2", "HEAD~3")
+	require.True(t, errors.As(err, &conflict), "%v", err)
+	require.NoError(t, r.Skip(ctx))
+	op, err = r.InProgressOperation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationNone, op)
+	reverts := subjects(other.LongHash + "..")
+	require.Len(t, reverts, 2)
+	assert.Contains(t, reverts[0], "feature 3")
+	assert.Contains(t, reverts[1], "feature 1")
+}
+
+// refExecutor replays a repository where only the given refs exist
+type refExecutor struct {
+	refs     []string
+	commands [][]string
+}
+
+func (e *refExecutor) Run(_ context.Context, cmd Command) error {
+	e.commands = append(e.commands, cmd.Args)
+	if len(cmd.Args) == 4 && cmd.Args[0] == "rev-parse" && cmd.Args[2] == "--verify" {
+		for _, ref := range e.refs {
+			if ref == cmd.Args[3] {
+				return nil
+			}
+		}
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func TestInProgressOperationExecutor(t *testing.T) {
+	ctx := context.TODO()
+	tests := []struct {
+		refs []string
+		want Operation
+	}{
+		{nil, OperationNone},
+		{[]string{"REBASE_HEAD", "CHERRY_PICK_HEAD"}, OperationRebase},
+		{[]string{"MERGE_HEAD"}, OperationMerge},
+		{[]string{"CHERRY_PICK_HEAD"}, OperationCherryPick},
+		{[]string{"REVERT_HEAD"}, OperationRevert},
+	}
+	path := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(path, ".git"), 0755))
+	for _, tt := range tests {
+		r, err := New(ctx, path, WithExecutor(&refExecutor{refs: tt.refs}))
+		require.NoError(t, err)
+		op, err := r.InProgressOperation(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, op, "%v", tt.refs)
+	}
+
+	e := &refExecutor{refs: []string{"CHERRY_PICK_HEAD"}}
+	r, err := New(ctx, path, WithExecutor(e))
+	require.NoError(t, err)
+	require.NoError(t, r.Continue(ctx))
+	assert.Equal(t, []string{"-c", "core.editor=true", "cherry-pick", "--continue"}, e.commands[len(e.commands)-1])
+}
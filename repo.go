@@ -2,6 +2,7 @@ package repo
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/md5"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,8 +24,6 @@ import (
 	"github.com/pkg/errors"
 )
 
-var urlRegExp = regexp.MustCompile(`https:\/\/[-a-zA-Z0-9@:%._\+#?&//=]*`)
-
 // Clone a git repository from the specified url to the destination path. Use Options to force the use of SSH Key and or PGP Key on this repo
 func Clone(ctx context.Context, path, cloneURL string, opts ...Option) (Repo, error) {
 	r := Repo{path: path, url: cloneURL}
@@ -214,138 +214,40 @@ func trimURL(fetchURL string) (string, error) {
 
 // Commits returns all the commit between
 func (r Repo) Commits(ctx context.Context, from, to string) ([]Commit, error) {
+	var commits []Commit
+	it := r.CommitsIter(ctx, from, to)
+	defer it.Close()
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// CommitsIter returns an iterator on the commits between from and to
+func (r Repo) CommitsIter(ctx context.Context, from, to string) *CommitIter {
 	if from == "0000000000000000000000000000000000000000" {
 		from = ""
 	}
 	if from != "" {
 		from = from + ".."
 	}
-	s, err := r.runCmd(ctx, "git", "rev-list", from+to)
-	if err != nil {
-		return nil, err
-	}
-	var commitsString []string
-	scanner := bufio.NewScanner(strings.NewReader(s))
-	for scanner.Scan() {
-		s := scanner.Text()
-		commitsString = append(commitsString, s)
-	}
-	err = scanner.Err()
-	if err != nil {
-		return nil, err
-	}
-
-	var commits []Commit
-	for _, c := range commitsString {
-		comm, err := r.GetCommit(ctx, c, CommitOption{DisableDiffDetail: true})
-		if err != nil {
-			return nil, err
-		}
-		commits = append(commits, comm)
-	}
-
-	return commits, err
+	return r.logIter(ctx, nil, CommitOption{DisableDiffDetail: true}, from+to, "--")
 }
 
 func (r Repo) CommitsBetween(ctx context.Context, from, to time.Time, branch string) ([]Commit, error) {
-	s, err := r.runCmd(ctx, "git", "log", branch, "--since", from.Format("2006-01-02"), "--until", to.Format("2006-01-02"), "--pretty=%H")
-	if err != nil {
-		return nil, err
-	}
-
-	var commitsString []string
-	scanner := bufio.NewScanner(strings.NewReader(s))
-	for scanner.Scan() {
-		s := scanner.Text()
-		commitsString = append(commitsString, s)
-	}
-	err = scanner.Err()
-	if err != nil {
-		return nil, err
-	}
-
-	var commits []Commit
-	for _, c := range commitsString {
-		comm, err := r.GetCommit(ctx, c, CommitOption{DisableDiffDetail: true})
-		if err != nil {
-			return nil, err
-		}
-		commits = append(commits, comm)
-	}
-
-	return commits, nil
+	return r.collectCommits(ctx, nil, CommitOption{DisableDiffDetail: true}, commitsBetweenArgs(from, to, branch)...)
 }
 
-func (r Repo) parseDiff(ctx context.Context, hash, diff string, opt CommitOption) (map[string]File, error) {
-	Files := make(map[string]File)
-
-	// Read line per line the last item
-	scanner := bufio.NewScanner(strings.NewReader(diff))
-	for scanner.Scan() {
-		s := scanner.Text()
-		var tuple []string
-		if strings.Contains(s, "\t") {
-			tuple = strings.SplitN(s, "\t", 2)
-		}
-		filename := strings.TrimSpace(tuple[1])
-		status := strings.TrimSpace(tuple[0])
-		diff, err := r.Diff(ctx, hash, filename)
-		if err != nil {
-			return nil, fmt.Errorf("unable to compute diff on file %s for commit %s: %v", filename, hash, err)
-		}
-
-		f := File{
-			Filename: filename,
-			Status:   status,
-			Diff:     diff,
-		}
-
-		//Scan the diff output
-		if !opt.DisableDiffDetail {
-			diffScanner := bufio.NewScanner(strings.NewReader(diff))
-			// Raise the MaxTokenSize value to 1024k (default is 64) to handle diff on serialized files (example: svg)
-			diffScanner.Buffer(nil, 1024*1024)
-			var currentHunk *Hunk
-			for diffScanner.Scan() {
-				line := diffScanner.Text()
-				switch {
-				case strings.HasPrefix(line, "@@ "):
-					line := strings.TrimPrefix(line, "@@ ")
-					if currentHunk != nil {
-						f.DiffDetail.Hunks = append(f.DiffDetail.Hunks, *currentHunk)
-						currentHunk = nil
-					}
-					currentHunk = new(Hunk)
-					currentHunk.Header = strings.TrimSpace(strings.Split(line, "@@")[0])
-					currentHunk.Content = strings.Join(strings.Split(line, "@@")[1:], "")
-				case currentHunk != nil && strings.HasPrefix(line, "-"):
-					currentHunk.RemovedLines = append(currentHunk.RemovedLines, strings.TrimPrefix(line, "-"))
-					currentHunk.Content += "\n" + line
-				case currentHunk != nil && strings.HasPrefix(line, "+"):
-					currentHunk.AddedLines = append(currentHunk.AddedLines, strings.TrimPrefix(line, "+"))
-					currentHunk.Content += "\n" + line
-				case currentHunk != nil:
-					currentHunk.Content += "\n" + line
-				}
-			}
-
-			if currentHunk != nil {
-				f.DiffDetail.Hunks = append(f.DiffDetail.Hunks, *currentHunk)
-			}
-
-			err = diffScanner.Err()
-			if err != nil {
-				return nil, fmt.Errorf("unable to compute diff on file %s for commit %s: %v", filename, hash, err)
-			}
-		}
-		Files[filename] = f
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
+// CommitsBetweenIter returns an iterator on the commits of the branch between two dates
+func (r Repo) CommitsBetweenIter(ctx context.Context, from, to time.Time, branch string) *CommitIter {
+	return r.logIter(ctx, nil, CommitOption{DisableDiffDetail: true}, commitsBetweenArgs(from, to, branch)...)
+}
 
-	return Files, nil
+func commitsBetweenArgs(from, to time.Time, branch string) []string {
+	return []string{branch, "--since", from.Format("2006-01-02"), "--until", to.Format("2006-01-02"), "--"}
 }
 
 // GetTag returns a tag
@@ -353,28 +255,11 @@ func (r Repo) GetTag(ctx context.Context, tagName string) (Tag, error) {
 	tagName = strings.TrimFunc(tagName, func(r rune) bool {
 		return r == '\n' || r == ' ' || r == '\t'
 	})
-	t := Tag{}
-	details, err := r.runCmd(ctx, "git", "show", tagName, "--pretty=||%at||%an||%ae||%s||%b||%H||%GK||", "--name-status")
-	if err != nil {
-		return t, err
-	}
-
-	splittedDetails := strings.SplitN(details, "||", 9)
-	splittedDetails = splittedDetails[1:]
-
-	ts, err := strconv.ParseInt(splittedDetails[0], 10, 64)
+	c, err := r.GetCommit(ctx, tagName+"^{commit}", CommitOption{DisableDiffDetail: true})
 	if err != nil {
-		return t, err
+		return Tag{}, err
 	}
-	t.Date = time.Unix(ts, 0)
-	t.Author = splittedDetails[1]
-	t.AuthorEmail = splittedDetails[2]
-	t.Subject = splittedDetails[3]
-	t.Body = splittedDetails[4]
-	t.LongHash = splittedDetails[5]
-	t.Hash = t.LongHash[:7]
-	t.GPGKeyID = splittedDetails[6]
-	return t, err
+	return Tag{Commit: c}, nil
 }
 
 // GetCommit returns a commit
@@ -382,108 +267,149 @@ func (r Repo) GetCommit(ctx context.Context, hash string, opts CommitOption) (Co
 	hash = strings.TrimFunc(hash, func(r rune) bool {
 		return r == '\n' || r == ' ' || r == '\t'
 	})
-	c := Commit{}
-	details, err := r.runCmd(ctx, "git", "show", hash, "--pretty=%at||%an||%ae||%s||%b||%GK||", "--name-status")
+	args := []string{"--max-count=1", hash, "--"}
+	commits, err := r.collectCommits(ctx, nil, opts, args...)
 	if err != nil {
-		return c, err
+		return Commit{}, err
 	}
-
-	c.LongHash = hash
-	c.Hash = hash[:7]
-
-	splittedDetails := strings.SplitN(details, "||", 7)
-
-	ts, err := strconv.ParseInt(splittedDetails[0], 10, 64)
-	if err != nil {
-		return c, err
+	if len(commits) == 0 {
+		// git log exits with 0 when the revision is not a commit (ie. a tree)
+		gitErr := newGitError("git", logArgs(opts, args...), "", "", fmt.Errorf("commit %s not found", hash))
+		gitErr.Kind = RefNotFound
+		return Commit{}, gitErr
 	}
-	c.Date = time.Unix(ts, 0)
-	c.Author = splittedDetails[1]
-	c.AuthorEmail = splittedDetails[2]
-	c.Subject = splittedDetails[3]
-	c.Body = splittedDetails[4]
-	c.GPGKeyID = splittedDetails[5]
-
-	fileList := strings.TrimSpace(splittedDetails[6])
-	c.Files, err = r.parseDiff(ctx, hash, fileList, opts)
-	return c, err
+	return commits[0], nil
 }
 
-func (r Repo) DiffSinceCommitMergeBase(ctx context.Context, hash string) (map[string]File, error) {
-	details, err := r.runCmd(ctx, "git", "diff", hash, "--name-status", "--merge-base")
-	if err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]File)
+// DiffSinceCommitMergeBase returns the files changed in the working tree since the merge base of the commit and HEAD
+func (r Repo) DiffSinceCommitMergeBase(ctx context.Context, hash string, opts ...DiffOpts) (map[string]File, error) {
+	opt := firstDiffOpts(opts)
+	opt.MergeBase = true
+	return r.DiffRefs(ctx, hash, WorkTree, opt)
+}
 
-	splittedFiles := strings.Split(details, "\n")
-	for _, fLine := range splittedFiles {
-		if len(strings.Trim(fLine, " ")) == 0 {
-			continue
-		}
-		fileData := strings.Split(fLine, "\t")
-		f := File{
-			Status:   fileData[0],
-			Filename: fileData[1],
-		}
-		result[f.Filename] = f
-	}
-	return result, nil
+// DiffSinceCommit returns the files changed in the working tree since the commit
+func (r Repo) DiffSinceCommit(ctx context.Context, hash string, opts ...DiffOpts) (map[string]File, error) {
+	return r.DiffRefs(ctx, hash, WorkTree, firstDiffOpts(opts))
 }
 
-func (r Repo) DiffSinceCommit(ctx context.Context, hash string) (map[string]File, error) {
-	details, err := r.runCmd(ctx, "git", "diff", hash, "--name-status")
-	if err != nil {
-		return nil, err
-	}
+// DiffBetweenBranches returns the files changed on branchFrom since it diverged from branchTo
+func (r Repo) DiffBetweenBranches(ctx context.Context, branchFrom, branchTo string, opts ...DiffOpts) (map[string]File, error) {
+	opt := firstDiffOpts(opts)
+	opt.MergeBase = true
+	return r.DiffRefs(ctx, branchTo, branchFrom, opt)
+}
 
-	result := make(map[string]File)
+// Index and WorkTree are the pseudo revisions of DiffRefs designating the index and the working tree
+const (
+	Index    = ":index"
+	WorkTree = ":worktree"
+)
 
-	splittedFiles := strings.Split(details, "\n")
-	for _, fLine := range splittedFiles {
-		if len(strings.Trim(fLine, " ")) == 0 {
-			continue
+// DiffRefs returns the files changed between two revisions, with their diff. from is a revision or Index, HEAD if empty.
+// to is a revision, Index or WorkTree, the working tree if empty. With opts.MergeBase, from is replaced by the merge base
+// of from and to (or HEAD if to is not a revision), as in the three-dot notation.
+func (r Repo) DiffRefs(ctx context.Context, from, to string, opts DiffOpts) (map[string]File, error) {
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = WorkTree
+	}
+	return r.diffRefs(ctx, from, to, CommitOption{DiffOpts: opts})
+}
+
+func (r Repo) diffRefs(ctx context.Context, from, to string, opt CommitOption) (map[string]File, error) {
+	opts := opt.DiffOpts
+	args := []string{"diff", "--raw", "--numstat", "-p", "-z", "--no-abbrev", "--src-prefix=a/", "--dst-prefix=b/", "--no-ext-diff", "--no-color"}
+	args = append(args, opt.renameArgs()...)
+	args = append(args, opts.args()...)
+	switch {
+	case from == WorkTree:
+		return nil, fmt.Errorf("invalid diff from the working tree")
+	case from == Index && to == WorkTree:
+		if opts.MergeBase {
+			return nil, fmt.Errorf("invalid merge base diff from the index")
+		}
+	case from == Index:
+		return nil, fmt.Errorf("invalid diff from the index to %s", to)
+	case to == Index:
+		args = append(args, "--cached")
+		fallthrough
+	case to == WorkTree:
+		if opts.MergeBase {
+			args = append(args, "--merge-base")
 		}
-		fileData := strings.Split(fLine, "\t")
-		f := File{
-			Status:   fileData[0],
-			Filename: fileData[1],
+		args = append(args, from)
+	default:
+		if opts.MergeBase {
+			args = append(args, "--merge-base")
 		}
-		result[f.Filename] = f
+		args = append(args, from, to)
 	}
-	return result, nil
-}
+	args = append(args, "--")
+	args = append(args, opts.pathspecs()...)
 
-func (r Repo) DiffBetweenBranches(ctx context.Context, branchFrom, branchTo string) (map[string]File, error) {
-	branchArg := branchTo + "..." + branchFrom
-	details, err := r.runCmd(ctx, "git", "diff", branchArg, "--name-status")
-	if err != nil {
+	// the output is not read with runCmd which rewrites the CR of the patches
+	out := new(bytes.Buffer)
+	if err := r.execCmd(ctx, Command{Name: "git", Args: args, Stdout: out}); err != nil {
 		return nil, err
 	}
+	// the files have been selected by git with the pathspecs, which may use magic
+	opt.DiffOpts.Paths, opt.DiffOpts.ExcludePaths = nil, nil
+	return parseFiles(out.Bytes(), opt)
+}
 
-	result := make(map[string]File)
-
-	splittedFiles := strings.Split(details, "\n")
-	for _, fLine := range splittedFiles {
-		if len(strings.Trim(fLine, " ")) == 0 {
-			continue
-		}
-		fileData := strings.Split(fLine, "\t")
-		f := File{
-			Status:   fileData[0],
-			Filename: fileData[1],
-		}
-		result[f.Filename] = f
+func firstDiffOpts(opts []DiffOpts) DiffOpts {
+	if len(opts) > 0 {
+		return opts[0]
 	}
-	return result, nil
+	return DiffOpts{}
 }
 
-func (r Repo) Diff(ctx context.Context, hash string, filename string) (string, error) {
+// Diff returns the diff of a file in a commit, or in the working tree if hash is empty.
+// If filename is empty, the diff of all the files selected by the DiffOpts pathspecs is returned.
+func (r Repo) Diff(ctx context.Context, hash string, filename string, opts ...DiffOpts) (string, error) {
+	opt := firstDiffOpts(opts)
+	var args []string
 	if hash == "" {
-		return r.runCmd(ctx, "git", "diff", "--pretty=", "--", filename)
+		args = []string{"diff", "--pretty="}
+	} else {
+		args = []string{"show", hash, "--pretty="}
+	}
+	if filename != "" {
+		opt.Paths = append([]string{filename}, opt.Paths...)
+	}
+	args = append(args, opt.args()...)
+	args = append(args, "--")
+	args = append(args, opt.pathspecs()...)
+	return r.runCmd(ctx, "git", args...)
+}
+
+// GrepDiff returns the lines added or removed by the commits of the range (ie. "v1.0.0..HEAD") matching the regexp.
+// The matches are ordered by commit, from the newest, then by filename.
+func (r Repo) GrepDiff(ctx context.Context, rangeSpec string, regexp *regexp.Regexp) ([]DiffMatch, error) {
+	var matches []DiffMatch
+	it := r.LogIter(ctx, LogOpts{Revisions: []string{rangeSpec}})
+	defer it.Close()
+	for it.Next() {
+		c := it.Commit()
+		filenames := make([]string, 0, len(c.Files))
+		for filename := range c.Files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			for _, m := range c.Files[filename].Grep(regexp) {
+				m.Commit = c.LongHash
+				matches = append(matches, m)
+			}
+		}
 	}
-	return r.runCmd(ctx, "git", "show", hash, "--pretty=", "--", filename)
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
 }
 
 // ExistsDiff returns true if there are no commited diff in the repo.
@@ -516,7 +442,7 @@ func (r Repo) CurrentBranch(ctx context.Context) (string, error) {
 func (r Repo) VerifyCommit(ctx context.Context, commit string) error {
 	_, err := r.runCmd(ctx, "git", "verify-commit", commit)
 	if err != nil {
-		return fmt.Errorf("commit not verify: %v", err)
+		return fmt.Errorf("commit not verify: %w", err)
 	}
 	return nil
 }
@@ -525,7 +451,7 @@ func (r Repo) VerifyCommit(ctx context.Context, commit string) error {
 func (r Repo) VerifyTag(ctx context.Context, tag string) (string, error) {
 	sha1, err := r.runCmd(ctx, "git", "rev-parse", "--verify", tag)
 	if err != nil {
-		return "", fmt.Errorf("tag not found: %v", err)
+		return "", fmt.Errorf("tag not found: %w", err)
 	}
 	return sha1[:len(sha1)-1], nil
 }
@@ -534,7 +460,7 @@ func (r Repo) VerifyTag(ctx context.Context, tag string) (string, error) {
 func (r Repo) FetchRemoteTags(ctx context.Context, remote string) error {
 	// Get tags from remote
 	if _, err := r.runCmd(ctx, "git", "fetch", "--tags", "--force", remote); err != nil {
-		return fmt.Errorf("unable to git fetch tags: %s", err)
+		return fmt.Errorf("unable to git fetch tags: %w", err)
 	}
 
 	return nil
@@ -545,17 +471,17 @@ func (r Repo) FetchRemoteTag(ctx context.Context, remote, tag string) error {
 	// delete tag if exist
 	if _, err := r.runCmd(ctx, "git", "rev-parse", "--verify", tag); err == nil {
 		if _, err := r.runCmd(ctx, "git", "tag", "-d", tag); err != nil {
-			return fmt.Errorf("unable to git delete tag: %s", err)
+			return fmt.Errorf("unable to git delete tag: %w", err)
 		}
 	}
 
 	// Get tag from remote
 	if _, err := r.runCmd(ctx, "git", "fetch", "--tags", "--force", remote); err != nil {
-		return fmt.Errorf("unable to git fetch tags: %s", err)
+		return fmt.Errorf("unable to git fetch tags: %w", err)
 	}
 
 	if _, err := r.runCmd(ctx, "git", "checkout", tag); err != nil {
-		return fmt.Errorf("unable to git checkout: %s", err)
+		return fmt.Errorf("unable to git checkout: %w", err)
 	}
 	return nil
 }
@@ -574,7 +500,7 @@ func (r Repo) LocalBranchExists(ctx context.Context, branch string) (exists, has
 // FetchRemoteBranch runs a git fetch then checkout the remote branch
 func (r Repo) FetchRemoteBranch(ctx context.Context, remote, branch string) error {
 	if _, err := r.runCmd(ctx, "git", "fetch", remote); err != nil {
-		return fmt.Errorf("unable to git fetch: %s", err)
+		return fmt.Errorf("unable to git fetch: %w", err)
 	}
 
 	branchExist, hasUpstream := r.LocalBranchExists(ctx, branch)
@@ -582,19 +508,19 @@ func (r Repo) FetchRemoteBranch(ctx context.Context, remote, branch string) erro
 		if hasUpstream {
 			_, err := r.runCmd(ctx, "git", "checkout", branch)
 			if err != nil {
-				return fmt.Errorf("unable to git checkout: %s", err)
+				return fmt.Errorf("unable to git checkout: %w", err)
 			}
 			return nil
 		}
 		// the branch exist but has no upstream. Delete it
 		if _, err := r.runCmd(ctx, "git", "branch", "-d", branch); err != nil {
-			return fmt.Errorf("unable to git delete: %s", err)
+			return fmt.Errorf("unable to git delete: %w", err)
 		}
 	}
 
 	_, err := r.runCmd(ctx, "git", "checkout", "-b", branch, "--track", remote+"/"+branch)
 	if err != nil {
-		return fmt.Errorf("unable to git checkout new branch: %s", err)
+		return fmt.Errorf("unable to git checkout new branch: %w", err)
 	}
 	return nil
 }
@@ -603,7 +529,7 @@ func (r Repo) FetchRemoteBranch(ctx context.Context, remote, branch string) erro
 func (r Repo) Checkout(ctx context.Context, branch string) error {
 	_, err := r.runCmd(ctx, "git", "checkout", branch)
 	if err != nil {
-		return fmt.Errorf("unable to git checkout: %s", err)
+		return fmt.Errorf("unable to git checkout: %w", err)
 	}
 	return nil
 }
@@ -612,7 +538,7 @@ func (r Repo) Checkout(ctx context.Context, branch string) error {
 func (r Repo) CheckoutNewBranch(ctx context.Context, branch string) error {
 	_, err := r.runCmd(ctx, "git", "checkout", "-b", branch)
 	if err != nil {
-		return fmt.Errorf("unable to git checkout: %s", err)
+		return fmt.Errorf("unable to git checkout: %w", err)
 	}
 	return nil
 }
@@ -621,7 +547,38 @@ func (r Repo) CheckoutNewBranch(ctx context.Context, branch string) error {
 func (r Repo) DeleteBranch(ctx context.Context, branch string) error {
 	_, err := r.runCmd(ctx, "git", "branch", "-d", branch)
 	if err != nil {
-		return fmt.Errorf("unable to delete branch: %s", err)
+		return fmt.Errorf("unable to delete branch: %w", err)
+	}
+	return nil
+}
+
+// ForceDeleteBranch deletes a branch on the local repository, even if it is not merged
+func (r Repo) ForceDeleteBranch(ctx context.Context, branch string) error {
+	_, err := r.runCmd(ctx, "git", "branch", "-D", branch)
+	if err != nil {
+		return fmt.Errorf("unable to delete branch: %w", err)
+	}
+	return nil
+}
+
+// RenameBranch renames a branch on the local repository
+func (r Repo) RenameBranch(ctx context.Context, oldName, newName string) error {
+	_, err := r.runCmd(ctx, "git", "branch", "-m", oldName, newName)
+	if err != nil {
+		return fmt.Errorf("unable to rename branch: %w", err)
+	}
+	return nil
+}
+
+// SetUpstream sets the upstream of a local branch (ie. origin/master). An empty upstream unsets it.
+func (r Repo) SetUpstream(ctx context.Context, branch, upstream string) error {
+	args := []string{"branch", "--unset-upstream", branch}
+	if upstream != "" {
+		args = []string{"branch", "--set-upstream-to=" + upstream, branch}
+	}
+	_, err := r.runCmd(ctx, "git", args...)
+	if err != nil {
+		return fmt.Errorf("unable to set branch upstream: %w", err)
 	}
 	return nil
 }
@@ -695,7 +652,7 @@ func (r Repo) Add(ctx context.Context, s ...string) error {
 	args := append([]string{"add"}, s...)
 	out, err := r.runCmd(ctx, "git", args...)
 	if err != nil {
-		return fmt.Errorf("command 'git add' failed: %v (%s)", err, out)
+		return fmt.Errorf("command 'git add' failed: %w (%s)", err, out)
 	}
 	return nil
 }
@@ -705,21 +662,27 @@ func (r Repo) Remove(ctx context.Context, s ...string) error {
 	args := append([]string{"rm", "-f", "-r"}, s...)
 	out, err := r.runCmd(ctx, "git", args...)
 	if err != nil {
-		return fmt.Errorf("command 'git rm' failed: %v (%s)", err, out)
+		return fmt.Errorf("command 'git rm' failed: %w (%s)", err, out)
 	}
 	return nil
 }
 
 // Commit the index
 func (r Repo) Commit(ctx context.Context, m string, opts ...Option) error {
+	// the trailers are those of this commit only
+	r.trailers = nil
 	for _, f := range opts {
 		if err := f(ctx, &r); err != nil {
 			return err
 		}
 	}
-	out, err := r.runCmd(ctx, "git", "commit", "-m", strconv.Quote(m))
+	args := []string{"commit", "-m", strconv.Quote(m)}
+	for _, t := range r.trailers {
+		args = append(args, "--trailer", t)
+	}
+	out, err := r.runCmd(ctx, "git", args...)
 	if err != nil {
-		return fmt.Errorf("command 'git commit' failed: %v (%s)", err, out)
+		return fmt.Errorf("command 'git commit' failed: %w (%s)", err, out)
 	}
 	return nil
 }
@@ -733,14 +696,7 @@ func (r Repo) PushTags(ctx context.Context, remote string, opts ...Option) error
 	}
 	out, err := r.runCmd(ctx, "git", "push", remote, "--tags")
 	if err != nil {
-		errS := fmt.Sprintf("%v", err)
-		URLS := urlRegExp.FindString(errS)
-		URL, errURL := url.Parse(URLS)
-		if errURL == nil {
-			URL.User = nil
-			errS = strings.Replace(errS, URLS, URL.String(), -1)
-		}
-		return fmt.Errorf("%s (%s)", errS, out)
+		return fmt.Errorf("%w (%s)", err, out)
 	}
 	return nil
 }
@@ -754,14 +710,7 @@ func (r Repo) Push(ctx context.Context, remote, branch string, opts ...Option) e
 	}
 	out, err := r.runCmd(ctx, "git", "push", "-f", "-u", remote, branch)
 	if err != nil {
-		errS := fmt.Sprintf("%v", err)
-		URLS := urlRegExp.FindString(errS)
-		URL, errURL := url.Parse(URLS)
-		if errURL == nil {
-			URL.User = nil
-			errS = strings.Replace(errS, URLS, URL.String(), -1)
-		}
-		return fmt.Errorf("%s (%s)", errS, out)
+		return fmt.Errorf("%w (%s)", err, out)
 	}
 	return nil
 }
@@ -776,7 +725,7 @@ func (r Repo) RemoteAdd(ctx context.Context, remote, branch, url string) error {
 	}
 	out, err := r.runCmd(ctx, "git", args...)
 	if err != nil {
-		return fmt.Errorf("command 'git remote add' failed: %v (%s)", err, out)
+		return fmt.Errorf("command 'git remote add' failed: %w (%s)", err, out)
 	}
 	return nil
 }
@@ -786,33 +735,75 @@ func (r Repo) RemoteShow(ctx context.Context, remote string) (string, error) {
 	args := []string{"remote", "show", remote}
 	out, err := r.runCmd(ctx, "git", args...)
 	if err != nil {
-		return out, fmt.Errorf("command 'git remote show' failed: %v (%s)", err, out)
+		return out, fmt.Errorf("command 'git remote show' failed: %w (%s)", err, out)
 	}
 	return out, nil
 }
 
-// Status run the git status command
-func (r Repo) Status(ctx context.Context) (string, error) {
-	args := []string{"status", "-s", "-uall"}
-	out, err := r.runCmd(ctx, "git", args...)
+// CurrentSnapshot returns the changes of the working tree from HEAD, staged or not. The untracked files are returned as added files.
+func (r Repo) CurrentSnapshot(ctx context.Context, opts CommitOption) (map[string]File, error) {
+	status, err := r.Status(ctx)
 	if err != nil {
-		return "", fmt.Errorf("command 'git status' failed: %v (%s)", err, out)
+		return nil, err
+	}
+	from := "HEAD"
+	if status.Head == "" {
+		// unborn branch, compare with the empty tree
+		out, err := r.runCmdWithStdin(ctx, strings.NewReader(""), "git", "hash-object", "-t", "tree", "--stdin")
+		if err != nil {
+			return nil, err
+		}
+		from = strings.TrimSpace(out)
 	}
-	return out, nil
-}
 
-func (r Repo) CurrentSnapshot(ctx context.Context, opts CommitOption) (map[string]File, error) {
-	diffFileList, err := r.Status(ctx)
+	var untracked []string
+	for _, f := range status.Files {
+		if f.Untracked {
+			untracked = append(untracked, f.Path)
+		}
+	}
+	if len(untracked) == 0 {
+		return r.diffRefs(ctx, from, WorkTree, opts)
+	}
+
+	// the untracked files are added with --intent-to-add to a copy of the index, to be diffed as added files
+	indexPath, err := r.runCmd(ctx, "git", "rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, err
+	}
+	indexPath = strings.TrimSpace(indexPath)
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(r.path, indexPath)
+	}
+	tmpDir, err := os.MkdirTemp("", "go-repo-index-")
 	if err != nil {
 		return nil, err
 	}
-	return r.parseDiff(ctx, "", diffFileList, opts)
+	defer os.RemoveAll(tmpDir)
+	tmpIndex := filepath.Join(tmpDir, "index")
+	index, err := os.ReadFile(indexPath)
+	switch {
+	case err == nil:
+		if err := os.WriteFile(tmpIndex, index, 0600); err != nil {
+			return nil, err
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	snapshot := r
+	snapshot.env = append(append([]string{}, r.env...), "GIT_INDEX_FILE="+tmpIndex)
+	paths := strings.NewReader(strings.Join(untracked, "\x00"))
+	if _, err := snapshot.runCmdWithStdin(ctx, paths, "git", "--literal-pathspecs", "add", "--intent-to-add", "--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+		return nil, err
+	}
+	return snapshot.diffRefs(ctx, from, WorkTree, opts)
 }
 
 func (r Repo) HasDiverged(ctx context.Context) (bool, error) {
 	out, err := r.runCmd(ctx, "git", "status")
 	if err != nil {
-		return false, fmt.Errorf("command 'git status' failed: %v (%s)", err, out)
+		return false, fmt.Errorf("command 'git status' failed: %w (%s)", err, out)
 	}
 	if strings.Contains(out, "diverged") {
 		return true, nil
@@ -850,11 +841,11 @@ func WithUser(email, name string) Option {
 	return func(ctx context.Context, r *Repo) error {
 		out, err := r.runCmd(ctx, "git", "config", "user.email", email)
 		if err != nil {
-			return fmt.Errorf("command 'git config user.email' failed: %v (%s)", err, out)
+			return fmt.Errorf("command 'git config user.email' failed: %w (%s)", err, out)
 		}
 		out, err = r.runCmd(ctx, "git", "config", "user.name", name)
 		if err != nil {
-			return fmt.Errorf("command 'git config user.name' failed: %v (%s)", err, out)
+			return fmt.Errorf("command 'git config user.name' failed: %w (%s)", err, out)
 		}
 		return err
 	}
@@ -871,7 +862,7 @@ func WithSignKey(keyId string) Option {
 	return func(ctx context.Context, r *Repo) error {
 		out, err := r.runCmd(ctx, "git", "config", "user.signingkey", keyId)
 		if err != nil {
-			return fmt.Errorf("command 'git config user.signingkey' failed: %v (%s)", err, out)
+			return fmt.Errorf("command 'git config user.signingkey' failed: %w (%s)", err, out)
 		}
 		return nil
 	}
@@ -924,6 +915,26 @@ func InstallPGPKey(privateKey []byte) Option {
 	}
 }
 
+// WithTrailer appends a trailer (ie. Signed-off-by, Co-authored-by) to the commit message. It only applies to the Commit it is given to, not to New or Clone.
+func WithTrailer(key, value string) Option {
+	return func(_ context.Context, r *Repo) error {
+		if key == "" || strings.ContainsAny(key, ":= \n") {
+			return fmt.Errorf("invalid trailer key %q", key)
+		}
+		r.trailers = append(r.trailers, key+"="+value)
+		return nil
+	}
+}
+
+// WithExecutor override the executor used to run the git commands. InProgressOperation, and Continue, Skip and Abort which rely on it,
+// also read the state files of the git directory (ie. rebase-merge, sequencer/todo) on the local filesystem.
+func WithExecutor(e Executor) Option {
+	return func(_ context.Context, r *Repo) error {
+		r.executor = e
+		return nil
+	}
+}
+
 // WithVerbose add some logs
 func WithVerbose(logger func(format string, i ...interface{})) Option {
 	return func(_ context.Context, r *Repo) error {
@@ -940,13 +951,43 @@ func (r Repo) log(format string, i ...interface{}) {
 }
 
 func (r Repo) Tags(ctx context.Context) ([]Tag, error) {
-	s, err := r.runCmd(ctx, "git", "show-ref", "--tags")
-	if err != nil {
+	it := r.TagsIter(ctx)
+	defer it.Close()
+	byName := make(map[string]Tag)
+	for it.Next() {
+		t := it.Tag()
+		byName[t.Message] = t
+	}
+	if err := it.Err(); err != nil {
 		return nil, err
 	}
 
-	var commitsString []string
-	var tagString []string
+	var tags []Tag
+	for _, name := range it.names {
+		t, has := byName[name]
+		if !has {
+			return nil, fmt.Errorf("commit not found for tag %s", name)
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// TagsIter returns an iterator on the tags. Tags pointing to the same commit are returned together.
+func (r Repo) TagsIter(ctx context.Context) *TagIter {
+	s, err := r.runCmd(ctx, "git", "show-ref", "--tags", "--dereference")
+	if err != nil {
+		var gitErr *GitError
+		// show-ref exits with 1 when there is no tag
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 && gitErr.Stderr == "" {
+			return &TagIter{commits: errCommitIter(nil)}
+		}
+		return &TagIter{commits: errCommitIter(err)}
+	}
+
+	// annotated tags are followed by a "<hash> <tag>^{}" line with the peeled commit
+	it := &TagIter{tags: make(map[string][]string)}
+	tagCommits := make(map[string]string)
 	scanner := bufio.NewScanner(strings.NewReader(s))
 	for scanner.Scan() {
 		s := scanner.Text()
@@ -954,24 +995,32 @@ func (r Repo) Tags(ctx context.Context) ([]Tag, error) {
 		h = strings.TrimSpace(h)
 		t := strings.Split(s, " ")[1]
 		t = strings.TrimSpace(t)
-		commitsString = append(commitsString, h)
-		tagString = append(tagString, t)
+		if strings.HasSuffix(t, "^{}") {
+			tagCommits[strings.TrimSuffix(t, "^{}")] = h
+			continue
+		}
+		it.names = append(it.names, t)
+		tagCommits[t] = h
 	}
-	err = scanner.Err()
-	if err != nil {
-		return nil, err
+	if err := scanner.Err(); err != nil {
+		it.commits = errCommitIter(err)
+		return it
 	}
 
-	var tags []Tag
-	for i, c := range commitsString {
-		t, err := r.GetCommit(ctx, c, CommitOption{DisableDiffDetail: true})
-		if err != nil {
-			return nil, err
+	var revs []string
+	for _, t := range it.names {
+		h := tagCommits[t]
+		if _, has := it.tags[h]; !has {
+			revs = append(revs, h)
 		}
-		tags = append(tags, Tag{Commit: t, Message: tagString[i]})
+		it.tags[h] = append(it.tags[h], t)
 	}
-
-	return tags, nil
+	if len(revs) == 0 {
+		it.commits = errCommitIter(nil)
+		return it
+	}
+	it.commits = r.logIter(ctx, revisionsReader(revs), CommitOption{DisableDiffDetail: true}, "--no-walk=unsorted", "--stdin")
+	return it
 }
 
 type SubmoduleOpt struct {
@@ -3,11 +3,14 @@ package repo
 import (
 	"bytes"
 	"context"
+	"io"
 	"io/ioutil"
 	"os"
 
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -113,6 +116,189 @@ func TestCurrentBranch(t *testing.T) {
 	assert.NotEmpty(t, b)
 }
 
+type fakeExecutor struct {
+	commands []Command
+	stdout   string
+}
+
+func (e *fakeExecutor) Run(_ context.Context, cmd Command) error {
+	e.commands = append(e.commands, cmd)
+	_, err := io.WriteString(cmd.Stdout, e.stdout)
+	return err
+}
+
+// initTestRepo initializes a local repository with a first commit of the files
+func initTestRepo(t *testing.T, files map[string]string) Repo {
+	path := t.TempDir()
+	out, err := exec.Command("git", "init", "--quiet", "--initial-branch=master", path).CombinedOutput()
+	require.NoError(t, err, string(out))
+	r, err := New(context.TODO(), path)
+	require.NoError(t, err)
+	commitTestFiles(t, r, "first", files)
+	return r
+}
+
+// commitTestFiles writes and commits the files
+func commitTestFiles(t *testing.T, r Repo, message string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, r.Write(name, strings.NewReader(content)))
+		require.NoError(t, r.Add(context.TODO(), name))
+	}
+	require.NoError(t, r.Commit(context.TODO(), message, WithUser("foo@bar.com", "foo.bar")))
+}
+
+func TestWithExecutor(t *testing.T) {
+	e := &fakeExecutor{stdout: "my-branch\n"}
+	r, err := New(context.TODO(), ".", WithExecutor(e))
+	require.NoError(t, err)
+	b, err := r.CurrentBranch(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "my-branch", b)
+	require.Len(t, e.commands, 1)
+	assert.Equal(t, "git", e.commands[0].Name)
+	assert.Equal(t, []string{"rev-parse", "--abbrev-ref", "HEAD"}, e.commands[0].Args)
+	assert.Equal(t, r.path, e.commands[0].Dir)
+}
+
+func TestDiffSinceCommitRenames(t *testing.T) {
+	e := &fakeExecutor{stdout: ":100644 100644 422c2b7ab3b3c668038da977e4e93a5fc623169c de980441c3ab03a8c07dda1ad27b8a11f39deb1e R086\x00old name.go\x00new\tname.go\x00" +
+		":100644 100644 422c2b7ab3b3c668038da977e4e93a5fc623169c 422c2b7ab3b3c668038da977e4e93a5fc623169c C100\x00a.go\x00b.go\x00" +
+		":000000 100644 0000000000000000000000000000000000000000 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 A\x00c.png\x00" +
+		":100644 000000 d5d0b8b4c4c9e936890870f6799cfbb5ba984470 0000000000000000000000000000000000000000 D\x00d.go\x00" +
+		"3\t1\t\x00old name.go\x00new\tname.go\x00" +
+		"0\t0\t\x00a.go\x00b.go\x00" +
+		"-\t-\tc.png\x00" +
+		"0\t12\td.go\x00"}
+	r, err := New(context.TODO(), ".", WithExecutor(e))
+	require.NoError(t, err)
+
+	files, err := r.DiffSinceCommit(context.TODO(), "HEAD~1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]File{
+		"new\tname.go": {
+			Filename: "new\tname.go", Status: "R086", Change: FileRenamed, OldFilename: "old name.go", Similarity: 86,
+			OldMode: "100644", NewMode: "100644", OldHash: "422c2b7ab3b3c668038da977e4e93a5fc623169c", NewHash: "de980441c3ab03a8c07dda1ad27b8a11f39deb1e",
+			Additions: 3, Deletions: 1,
+		},
+		"b.go": {
+			Filename: "b.go", Status: "C100", Change: FileCopied, OldFilename: "a.go", Similarity: 100,
+			OldMode: "100644", NewMode: "100644", OldHash: "422c2b7ab3b3c668038da977e4e93a5fc623169c", NewHash: "422c2b7ab3b3c668038da977e4e93a5fc623169c",
+		},
+		"c.png": {Filename: "c.png", Status: "A", Change: FileAdded, NewMode: "100644", NewHash: "d5d0b8b4c4c9e936890870f6799cfbb5ba984470", IsBinary: true},
+		"d.go":  {Filename: "d.go", Status: "D", Change: FileDeleted, OldMode: "100644", OldHash: "d5d0b8b4c4c9e936890870f6799cfbb5ba984470", Deletions: 12},
+	}, files)
+	require.Len(t, e.commands, 1)
+	assert.Equal(t, []string{"diff", "--raw", "--numstat", "-p", "-z", "--no-abbrev", "--src-prefix=a/", "--dst-prefix=b/", "--no-ext-diff", "--no-color", "HEAD~1", "--"}, e.commands[0].Args)
+}
+
+func TestDiffWithOpts(t *testing.T) {
+	e := &fakeExecutor{}
+	r, err := New(context.TODO(), ".", WithExecutor(e))
+	require.NoError(t, err)
+
+	_, err = r.Diff(context.TODO(), "HEAD", "repo.go", DiffOpts{Whitespace: IgnoreSpaceChange, Algorithm: DiffPatience})
+	require.NoError(t, err)
+	_, err = r.Diff(context.TODO(), "", "", DiffOpts{ExcludePaths: []string{"vendor"}})
+	require.NoError(t, err)
+	_, err = r.DiffBetweenBranches(context.TODO(), "feature", "master", DiffOpts{Paths: []string{"*.go"}})
+	require.NoError(t, err)
+
+	require.Len(t, e.commands, 3)
+	assert.Equal(t, []string{"show", "HEAD", "--pretty=", "--ignore-space-change", "--diff-algorithm=patience", "--", "repo.go"}, e.commands[0].Args)
+	assert.Equal(t, []string{"diff", "--pretty=", "--", ".", ":(exclude)vendor"}, e.commands[1].Args)
+	assert.Equal(t, []string{"diff", "--raw", "--numstat", "-p", "-z", "--no-abbrev", "--src-prefix=a/", "--dst-prefix=b/", "--no-ext-diff", "--no-color", "--merge-base", "master", "feature", "--", "*.go"}, e.commands[2].Args)
+}
+
+func TestDiffRefs(t *testing.T) {
+	args := []string{"diff", "--raw", "--numstat", "-p", "-z", "--no-abbrev", "--src-prefix=a/", "--dst-prefix=b/", "--no-ext-diff", "--no-color"}
+	tests := []struct {
+		from, to  string
+		mergeBase bool
+		want      []string
+	}{
+		{"", "", false, []string{"HEAD", "--"}},
+		{"v1.0.0", "HEAD", false, []string{"v1.0.0", "HEAD", "--"}},
+		{"master", "feature", true, []string{"--merge-base", "master", "feature", "--"}},
+		{"HEAD", Index, false, []string{"--cached", "HEAD", "--"}},
+		{"master", Index, true, []string{"--cached", "--merge-base", "master", "--"}},
+		{"master", WorkTree, true, []string{"--merge-base", "master", "--"}},
+		{Index, WorkTree, false, []string{"--"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" "+tt.to, func(t *testing.T) {
+			e := &fakeExecutor{}
+			r, err := New(context.TODO(), ".", WithExecutor(e))
+			require.NoError(t, err)
+			_, err = r.DiffRefs(context.TODO(), tt.from, tt.to, DiffOpts{MergeBase: tt.mergeBase})
+			require.NoError(t, err)
+			require.Len(t, e.commands, 1)
+			assert.Equal(t, append(append([]string{}, args...), tt.want...), e.commands[0].Args)
+		})
+	}
+
+	r, err := New(context.TODO(), ".", WithExecutor(&fakeExecutor{}))
+	require.NoError(t, err)
+	_, err = r.DiffRefs(context.TODO(), WorkTree, "HEAD", DiffOpts{})
+	assert.Error(t, err)
+	_, err = r.DiffRefs(context.TODO(), Index, "HEAD", DiffOpts{})
+	assert.Error(t, err)
+	_, err = r.DiffRefs(context.TODO(), Index, WorkTree, DiffOpts{MergeBase: true})
+	assert.Error(t, err)
+}
+
+func TestDiffRefsCRLF(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a.txt": "one\r\ntwo\r\n"})
+	commitTestFiles(t, r, "crlf", map[string]string{"a.txt": "one\r\n2\r\n"})
+
+	c, err := r.GetCommit(ctx, "HEAD", CommitOption{})
+	require.NoError(t, err)
+	files, err := r.DiffRefs(ctx, "HEAD~1", "HEAD", DiffOpts{})
+	require.NoError(t, err)
+	assert.Contains(t, files["a.txt"].Diff, "+2\r\n")
+	assert.Equal(t, c.Files["a.txt"].Diff, files["a.txt"].Diff)
+	assert.Equal(t, c.Files["a.txt"].DiffDetail, files["a.txt"].DiffDetail)
+}
+
+func TestDiffRefsPathspecMagic(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"README": "a\n", "c.txt": "c\n"})
+	require.NoError(t, os.MkdirAll(filepath.Join(r.path, "src", "b"), 0755))
+	commitTestFiles(t, r, "change", map[string]string{"README": "b\n", "src/a.go": "package a\n", "src/b/b.go": "package b\n", "c.txt": "d\n"})
+
+	tests := []struct {
+		opts DiffOpts
+		want []string
+	}{
+		{DiffOpts{Paths: []string{":(icase)readme"}}, []string{"README"}},
+		{DiffOpts{Paths: []string{":(glob)**/*.go"}}, []string{"src/a.go", "src/b/b.go"}},
+		{DiffOpts{Paths: []string{":(top)src"}}, []string{"src/a.go", "src/b/b.go"}},
+		{DiffOpts{Paths: []string{":/src/b"}}, []string{"src/b/b.go"}},
+		{DiffOpts{ExcludePaths: []string{":(glob)**/*.go", ":(icase)readme"}}, []string{"c.txt"}},
+		{DiffOpts{Paths: []string{"src"}, ExcludePaths: []string{":/src/b"}}, []string{"src/a.go"}},
+	}
+	for _, tt := range tests {
+		files, err := r.DiffRefs(ctx, "HEAD~1", "HEAD", tt.opts)
+		require.NoError(t, err)
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, tt.want, names, "%+v", tt.opts)
+	}
+
+	// the files of the commits are filtered without git, which does not support the magic
+	_, err := r.GetCommit(ctx, "HEAD", CommitOption{DiffOpts: DiffOpts{Paths: []string{":(icase)readme"}}})
+	assert.Error(t, err)
+	_, err = r.Log(ctx, LogOpts{CommitOption: CommitOption{DiffOpts: DiffOpts{ExcludePaths: []string{":(glob)**/*.go"}}}})
+	assert.Error(t, err)
+	c, err := r.GetCommit(ctx, "HEAD", CommitOption{DiffOpts: DiffOpts{Paths: []string{"*.go"}, ExcludePaths: []string{"src/b"}}})
+	require.NoError(t, err)
+	require.Len(t, c.Files, 1)
+	assert.Contains(t, c.Files, "src/a.go")
+}
+
 func TestFetchRemoteTags(t *testing.T) {
 	path := filepath.Join(os.TempDir(), "testdata", t.Name())
 	defer os.RemoveAll(path)
@@ -407,11 +593,16 @@ func TestVerifyCommit(t *testing.T) {
 	require.NoError(t, r.Add(context.TODO(), "README.md"))
 	require.NoError(t, r.Commit(context.TODO(), "This is a test", WithSignKey(keyId)))
 
-	commit, err := r.LatestCommit(context.TODO(), CommitOption{})
+	commit, err := r.LatestCommit(context.TODO(), CommitOption{LoadTrustLevel: true})
 	require.NoError(t, err)
 
 	require.NoError(t, r.VerifyCommit(context.TODO(), commit.Hash))
 	require.NoError(t, err)
+
+	assert.Equal(t, SignatureGood, commit.Signature.Status)
+	assert.Equal(t, "go-repo-test@local.net", commit.Signature.Signer)
+	assert.Equal(t, "ultimate", commit.Signature.TrustLevel)
+	assert.Contains(t, commit.Signature.Raw, "-----BEGIN PGP SIGNATURE-----")
 }
 
 func TestDefaultBranch(t *testing.T) {
@@ -607,7 +798,7 @@ func TestRemove(t *testing.T) {
 	status, err := r.Status(context.TODO())
 	require.NoError(t, err)
 	t.Log(status)
-	assert.True(t, strings.Contains(status, "D"))
+	assert.Contains(t, status.Files, StatusFile{Path: "cmd.go", Staged: FileDeleted})
 }
 
 func TestCommitWithUser(t *testing.T) {
@@ -628,6 +819,34 @@ func TestCommitWithUser(t *testing.T) {
 
 }
 
+func TestCommitWithTrailers(t *testing.T) {
+	ctx := context.TODO()
+	r := initTestRepo(t, map[string]string{"a": "a\n"})
+
+	require.NoError(t, r.Write("b", strings.NewReader("b\n")))
+	require.NoError(t, r.Add(ctx, "b"))
+	require.NoError(t, r.Commit(ctx, "This is a test",
+		WithTrailer("Signed-off-by", "foo.bar <foo@bar.com>"),
+		WithTrailer("Co-authored-by", "bar.foo <bar@foo.com>"),
+	))
+	commit, err := r.LatestCommit(ctx, CommitOption{})
+	require.NoError(t, err)
+	assert.Equal(t, "foo.bar <foo@bar.com>", commit.Trailers.Get("Signed-off-by"))
+	assert.Equal(t, []Identity{{Name: "bar.foo", Email: "bar@foo.com"}}, commit.CoAuthors())
+
+	// the trailers do not apply to the next commits, even given to New
+	r, err = New(ctx, r.path, WithTrailer("Signed-off-by", "foo.bar <foo@bar.com>"))
+	require.NoError(t, err)
+	require.NoError(t, r.Write("c", strings.NewReader("c\n")))
+	require.NoError(t, r.Add(ctx, "c"))
+	require.NoError(t, r.Commit(ctx, "This is another test"))
+	commit, err = r.LatestCommit(ctx, CommitOption{})
+	require.NoError(t, err)
+	assert.Empty(t, commit.Trailers)
+
+	assert.Error(t, r.Commit(ctx, "This is a test", WithTrailer("Signed off", "foo.bar <foo@bar.com>")))
+}
+
 func TestCommitsBetween(t *testing.T) {
 	path := filepath.Join(os.TempDir(), "testdata", t.Name())
 	defer os.RemoveAll(path)
@@ -642,6 +861,47 @@ func TestCommitsBetween(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCommits(t *testing.T) {
+	r, err := New(context.TODO(), ".")
+	require.NoError(t, err)
+
+	commits, err := r.Commits(context.TODO(), "", "HEAD")
+	require.NoError(t, err)
+	require.NotEmpty(t, commits)
+
+	for _, c := range commits[:1] {
+		expected, err := r.GetCommit(context.TODO(), c.LongHash, CommitOption{DisableDiffDetail: true})
+		require.NoError(t, err)
+		assert.Equal(t, expected, c)
+	}
+}
+
+func TestCommitsIter(t *testing.T) {
+	r, err := New(context.TODO(), ".")
+	require.NoError(t, err)
+
+	it := r.CommitsIter(context.TODO(), "", "HEAD")
+	var n int
+	for it.Next() {
+		assert.NotEmpty(t, it.Commit().LongHash)
+		n++
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+
+	commits, err := r.Commits(context.TODO(), "", "HEAD")
+	require.NoError(t, err)
+	assert.Len(t, commits, n)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	it = r.CommitsIter(ctx, "", "HEAD")
+	defer it.Close()
+	require.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
+
 func TestTags(t *testing.T) {
 	path := filepath.Join(os.TempDir(), "testdata", t.Name())
 	defer os.RemoveAll(path)
@@ -779,3 +1039,17 @@ func TestDiffSinceCommit(t *testing.T) {
 	_, has = results["file2.md"]
 	require.True(t, has)
 }
+
+func TestGrepDiff(t *testing.T) {
+	r, err := New(context.TODO(), ".")
+	require.NoError(t, err)
+
+	matches, err := r.GrepDiff(context.TODO(), "HEAD", regexp.MustCompile(`^package repo$`))
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+	for _, m := range matches {
+		assert.NotEmpty(t, m.Commit)
+		assert.True(t, strings.HasSuffix(m.Filename, ".go"), m.Filename)
+		assert.Equal(t, 1, m.LineNo)
+	}
+}
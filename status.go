@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type StatusOpts struct {
+	// Ignored lists the ignored files
+	Ignored bool
+}
+
+// StatusReport is the state of the working tree, as reported by git status --porcelain=v2
+type StatusReport struct {
+	// Branch is the current branch, empty if HEAD is detached
+	Branch string
+	// Head is the current commit, empty on an unborn branch
+	Head string
+	// Upstream is the upstream branch of the current branch (ie. origin/master), if any
+	Upstream string
+	// Ahead and Behind are the number of commits of the branch which are not in its upstream, and the other way around
+	Ahead  int
+	Behind int
+	// Files are the changed and unmerged files, followed by the untracked and the ignored files
+	Files []StatusFile
+}
+
+// StatusFile is the state of a file of the working tree
+type StatusFile struct {
+	Path string
+	// OrigPath is the source of a renamed or copied file
+	OrigPath string
+	// Similarity is the similarity percentage of a renamed or copied file
+	Similarity int
+	// Staged is the change of the index from HEAD, empty if the file is not staged.
+	// Unstaged is the change of the working tree from the index, empty if the file is not modified.
+	// For an unmerged file, they are the changes of each side of the merge (ie. U and U when both sides modified the file).
+	Staged   FileStatus
+	Unstaged FileStatus
+	// Conflicted is set for an unmerged file
+	Conflicted bool
+	Untracked  bool
+	Ignored    bool
+}
+
+// IsClean returns true if there is no changed, unmerged or untracked file
+func (s StatusReport) IsClean() bool {
+	for _, f := range s.Files {
+		if !f.Ignored {
+			return false
+		}
+	}
+	return true
+}
+
+// Conflicts returns the unmerged files
+func (s StatusReport) Conflicts() []StatusFile {
+	var files []StatusFile
+	for _, f := range s.Files {
+		if f.Conflicted {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Status returns the state of the current branch and of the working tree
+func (r Repo) Status(ctx context.Context, opts ...StatusOpts) (StatusReport, error) {
+	var opt StatusOpts
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	args := []string{"status", "--porcelain=v2", "--branch", "-z", "-uall"}
+	if opt.Ignored {
+		args = append(args, "--ignored")
+	}
+	// the output is not read with runCmd which rewrites the CR of the paths
+	out := new(bytes.Buffer)
+	if err := r.execCmd(ctx, Command{Name: "git", Args: args, Stdout: out}); err != nil {
+		return StatusReport{}, fmt.Errorf("command 'git status' failed: %w", err)
+	}
+	return parseStatus(out.String())
+}
+
+// parseStatus parses the git status --porcelain=v2 --branch -z output
+func parseStatus(out string) (StatusReport, error) {
+	var s StatusReport
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		if rec == "" {
+			continue
+		}
+		switch rec[0] {
+		case '#':
+			// # branch.<key> <value>
+			key, value, _ := strings.Cut(strings.TrimPrefix(rec, "# "), " ")
+			switch key {
+			case "branch.oid":
+				if value != "(initial)" {
+					s.Head = value
+				}
+			case "branch.head":
+				if value != "(detached)" {
+					s.Branch = value
+				}
+			case "branch.upstream":
+				s.Upstream = value
+			case "branch.ab":
+				// +<ahead> -<behind>
+				if _, err := fmt.Sscanf(value, "+%d -%d", &s.Ahead, &s.Behind); err != nil {
+					return s, fmt.Errorf("unable to parse status header %q: %w", rec, err)
+				}
+			}
+		case '1':
+			// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+			fields := strings.SplitN(rec, " ", 9)
+			if len(fields) != 9 || len(fields[1]) != 2 {
+				return s, fmt.Errorf("unable to parse status entry %q", rec)
+			}
+			s.Files = append(s.Files, StatusFile{
+				Path:     fields[8],
+				Staged:   statusCode(fields[1][0]),
+				Unstaged: statusCode(fields[1][1]),
+			})
+		case '2':
+			// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>, followed by the original path
+			fields := strings.SplitN(rec, " ", 10)
+			if len(fields) != 10 || len(fields[1]) != 2 || len(fields[8]) < 2 || i+1 >= len(records) {
+				return s, fmt.Errorf("unable to parse status entry %q", rec)
+			}
+			f := StatusFile{
+				Path:     fields[9],
+				OrigPath: records[i+1],
+				Staged:   statusCode(fields[1][0]),
+				Unstaged: statusCode(fields[1][1]),
+			}
+			f.Similarity, _ = strconv.Atoi(fields[8][1:])
+			s.Files = append(s.Files, f)
+			i++
+		case 'u':
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			fields := strings.SplitN(rec, " ", 11)
+			if len(fields) != 11 || len(fields[1]) != 2 {
+				return s, fmt.Errorf("unable to parse status entry %q", rec)
+			}
+			s.Files = append(s.Files, StatusFile{
+				Path:       fields[10],
+				Staged:     statusCode(fields[1][0]),
+				Unstaged:   statusCode(fields[1][1]),
+				Conflicted: true,
+			})
+		case '?':
+			s.Files = append(s.Files, StatusFile{Path: strings.TrimPrefix(rec, "? "), Untracked: true})
+		case '!':
+			s.Files = append(s.Files, StatusFile{Path: strings.TrimPrefix(rec, "! "), Ignored: true})
+		default:
+			return s, fmt.Errorf("unable to parse status entry %q", rec)
+		}
+	}
+	return s, nil
+}
+
+func statusCode(c byte) FileStatus {
+	if c == '.' {
+		return ""
+	}
+	return FileStatus(c)
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// Trailers are the trailers of a commit message (ie. Signed-off-by, Co-authored-by, Change-Id), as parsed by git interpret-trailers.
+// A key may have several values. Keys are case insensitive, they are stored with the spelling of their first occurrence.
+type Trailers map[string][]string
+
+// Get returns the first value of the trailer key
+func (t Trailers) Get(key string) string {
+	values := t.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Values returns all the values of the trailer key
+func (t Trailers) Values(key string) []string {
+	return t[t.key(key)]
+}
+
+// key returns the spelling of key used in t
+func (t Trailers) key(key string) string {
+	if _, has := t[key]; has {
+		return key
+	}
+	for k := range t {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// parseTrailers parses the output of %(trailers:only,unfold,separator=%x1f,key_value_separator=%x1d)
+func parseTrailers(s string) Trailers {
+	if s == "" {
+		return nil
+	}
+	trailers := make(Trailers)
+	for _, t := range strings.Split(s, "\x1f") {
+		kv := strings.SplitN(t, "\x1d", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := trailers.key(kv[0])
+		trailers[key] = append(trailers[key], strings.TrimSpace(kv[1]))
+	}
+	return trailers
+}
+
+// Identity is a name and an email address
+type Identity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// parseIdentity parses "Name <email>", the email is optional
+func parseIdentity(s string) Identity {
+	s = strings.TrimSpace(s)
+	if addr, err := mail.ParseAddress(s); err == nil {
+		return Identity{Name: addr.Name, Email: addr.Address}
+	}
+	if i := strings.LastIndex(s, "<"); i >= 0 && strings.HasSuffix(s, ">") {
+		return Identity{Name: strings.TrimSpace(s[:i]), Email: s[i+1 : len(s)-1]}
+	}
+	return Identity{Name: s}
+}
+
+// CoAuthors returns the identities of the Co-authored-by trailers
+func (c Commit) CoAuthors() []Identity {
+	var identities []Identity
+	for _, v := range c.Trailers.Values("Co-authored-by") {
+		identities = append(identities, parseIdentity(v))
+	}
+	return identities
+}
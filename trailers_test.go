@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTrailers(t *testing.T) {
+	trailers := parseTrailers("Signed-off-by\x1dfoo.bar <foo@bar.com>\x1fCo-authored-by\x1dbar.foo <bar@foo.com>\x1fsigned-off-by\x1d bar.foo <bar@foo.com>\x1fChange-Id\x1dI123")
+	assert.Equal(t, []string{"foo.bar <foo@bar.com>", "bar.foo <bar@foo.com>"}, trailers.Values("Signed-off-by"))
+	assert.Equal(t, "I123", trailers.Get("change-id"))
+	assert.Equal(t, "", trailers.Get("Reviewed-by"))
+	assert.Nil(t, parseTrailers(""))
+
+	c := Commit{Trailers: trailers}
+	assert.Equal(t, []Identity{{Name: "bar.foo", Email: "bar@foo.com"}}, c.CoAuthors())
+}
+
+func Test_parseIdentity(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Identity
+	}{
+		{"foo.bar <foo@bar.com>", Identity{Name: "foo.bar", Email: "foo@bar.com"}},
+		{"François Samin <francois@samin.fr>", Identity{Name: "François Samin", Email: "francois@samin.fr"}},
+		{"<foo@bar.com>", Identity{Email: "foo@bar.com"}},
+		{"foo.bar", Identity{Name: "foo.bar"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseIdentity(tt.s))
+		})
+	}
+}
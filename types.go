@@ -7,60 +7,187 @@ import (
 
 // Repo is the main type of this lib
 type Repo struct {
-	path    string
-	url     string
-	sshKey  *sshKey
-	pgpKey  *pgpKey
-	verbose bool
-	logger  func(format string, i ...interface{})
-	depth   int
+	path     string
+	url      string
+	sshKey   *sshKey
+	pgpKey   *pgpKey
+	verbose  bool
+	logger   func(format string, i ...interface{})
+	depth    int
+	executor Executor
+	trailers []string
+	// env are environment variables added to the git commands
+	env []string
 }
 
 // Commit represent a git commit
 type Commit struct {
-	LongHash    string
-	Hash        string
-	Author      string
-	AuthorEmail string
-	Subject     string
-	Body        string
-	Date        time.Time
-	Files       map[string]File
-	GPGKeyID    string
+	LongHash       string
+	Hash           string
+	Author         string
+	AuthorEmail    string
+	Subject        string
+	Body           string
+	Date           time.Time
+	Committer      string
+	CommitterEmail string
+	CommitterDate  time.Time
+	Parents        []string
+	TreeHash       string
+	IsMerge        bool
+	Files          map[string]File
+	GPGKeyID       string
+	Signature      Signature
+	Trailers       Trailers
+}
+
+// SignatureStatus is the verification status of a commit signature, as reported by git log %G?
+type SignatureStatus string
+
+const (
+	SignatureGood            SignatureStatus = "G"
+	SignatureBad             SignatureStatus = "B"
+	SignatureUnknownValidity SignatureStatus = "U"
+	SignatureExpired         SignatureStatus = "X"
+	SignatureExpiredKey      SignatureStatus = "Y"
+	SignatureRevokedKey      SignatureStatus = "R"
+	SignatureCannotCheck     SignatureStatus = "E"
+	SignatureNone            SignatureStatus = "N"
+)
+
+// Signature represents the signature of a commit and its verification
+type Signature struct {
+	Status                SignatureStatus
+	Signer                string
+	KeyID                 string
+	Fingerprint           string
+	PrimaryKeyFingerprint string
+	// TrustLevel is the trust level of the key of a good signature, it is only computed with CommitOption.LoadTrustLevel
+	TrustLevel string
+	// Message is the raw output of the signature verification
+	Message string
+	// Raw is the armored signature stored in the commit object
+	Raw string
 }
 
 type CommitOption struct {
 	DisableDiffDetail bool
+	// DisableFiles skips the computation of the files changed by the commits
+	DisableFiles bool
+	// DisableRenames disables the rename detection, renamed files are reported as deleted and added
+	DisableRenames bool
+	// RenameThreshold is the similarity percentage for a deleted and added pair of files to be reported as renamed. Default to git's 50%
+	RenameThreshold int
+	// CopyThreshold enables the copy detection among the modified files, with the given similarity percentage
+	CopyThreshold int
+	// DiffOpts tunes the diff of the files. Its pathspecs filter the files of the commits, not the commits themselves.
+	// Only paths and wildcards are supported, a pathspec with magic (ie. ":(icase)readme") returns an error.
+	DiffOpts DiffOpts
+	// LoadTrustLevel computes the Signature.TrustLevel of the good signatures. It runs a git command per signed commit.
+	LoadTrustLevel bool
+}
+
+// DiffOpts is a optional struct for the diff of files
+type DiffOpts struct {
+	Whitespace       WhitespaceMode
+	IgnoreBlankLines bool
+	// ContextLines is the number of context lines around the changes. Default to git's 3
+	ContextLines *int
+	Algorithm    DiffAlgorithm
+	// WordDiff outputs a plain word diff in File.Diff. DiffDetail is not computed for word diffs.
+	WordDiff bool
+	// Paths are the pathspecs of the files to include, all by default
+	Paths []string
+	// ExcludePaths are the pathspecs of the files to exclude
+	ExcludePaths []string
+	// MergeBase compares from the merge base of the revisions, as the three-dot notation does. It is only used by DiffRefs.
+	MergeBase bool
 }
 
+// WhitespaceMode defines how whitespace changes are ignored
+type WhitespaceMode string
+
+const (
+	WhitespaceDefault         WhitespaceMode = ""
+	IgnoreAllSpace            WhitespaceMode = "ignore-all-space"
+	IgnoreSpaceChange         WhitespaceMode = "ignore-space-change"
+	IgnoreSpaceAtEOL          WhitespaceMode = "ignore-space-at-eol"
+	IgnoreCarriageReturnAtEOL WhitespaceMode = "ignore-cr-at-eol"
+)
+
+// DiffAlgorithm is a git diff algorithm
+type DiffAlgorithm string
+
+const (
+	DiffDefault   DiffAlgorithm = ""
+	DiffMyers     DiffAlgorithm = "myers"
+	DiffMinimal   DiffAlgorithm = "minimal"
+	DiffPatience  DiffAlgorithm = "patience"
+	DiffHistogram DiffAlgorithm = "histogram"
+)
+
 type Tag struct {
 	Message string
 	Commit
 }
 
 type File struct {
-	Filename   string
-	Status     string
+	Filename string
+	// Status is the raw git status, with the similarity score of renames and copies (ie. R086)
+	Status string
+	// Change is the typed Status
+	Change FileStatus
+	// OldFilename is the source of a renamed or copied file
+	OldFilename string
+	// Similarity is the similarity percentage of a renamed or copied file
+	Similarity int
+	// OldMode and NewMode are the octal git modes of the file (ie. 100644), empty for an added file or a deleted file
+	OldMode string
+	NewMode string
+	// OldHash and NewHash are the blob hashes of the file, empty for an added file, a deleted file or a file of the working tree
+	OldHash string
+	NewHash string
+	// IsBinary is set when git considers the file as binary. Binary files have no DiffDetail.
+	IsBinary bool
+	// Additions and Deletions are the number of added and deleted lines, as in git diff --numstat
+	Additions  int
+	Deletions  int
 	Diff       string
 	DiffDetail FileDiffDetail
 }
 
+// FileStatus is the kind of change made on a file
+type FileStatus string
+
+const (
+	FileAdded       FileStatus = "A"
+	FileModified    FileStatus = "M"
+	FileDeleted     FileStatus = "D"
+	FileRenamed     FileStatus = "R"
+	FileCopied      FileStatus = "C"
+	FileTypeChanged FileStatus = "T"
+	FileUnmerged    FileStatus = "U"
+)
+
 type FileDiffDetail struct {
 	Hunks []Hunk
 }
 
+// Matches returns the hunks having an added or removed line matching the regexp, and whether added and removed lines match
 func (d FileDiffDetail) Matches(regexp *regexp.Regexp) (hunks []Hunk, addedLinewMatch bool, removedLinewMatch bool) {
 	for _, h := range d.Hunks {
 		var hunkMatches bool
 		for _, l := range h.RemovedLines {
 			if regexp.MatchString(l) {
 				removedLinewMatch = true
+				hunkMatches = true
 				break
 			}
 		}
 		for _, l := range h.AddedLines {
 			if regexp.MatchString(l) {
 				addedLinewMatch = true
+				hunkMatches = true
 				break
 			}
 		}
@@ -71,11 +198,80 @@ func (d FileDiffDetail) Matches(regexp *regexp.Regexp) (hunks []Hunk, addedLinew
 	return hunks, addedLinewMatch, removedLinewMatch
 }
 
+// Grep returns the added and removed lines matching the regexp
+func (d FileDiffDetail) Grep(regexp *regexp.Regexp) []DiffMatch {
+	var matches []DiffMatch
+	for _, h := range d.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == LineContext || !regexp.MatchString(l.Content) {
+				continue
+			}
+			m := DiffMatch{Hunk: h, Side: l.Kind, Line: l, LineNo: l.NewLineNo}
+			if l.Kind == LineRemoved {
+				m.LineNo = l.OldLineNo
+			}
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Grep returns the added and removed lines of the file matching the regexp
+func (f File) Grep(regexp *regexp.Regexp) []DiffMatch {
+	matches := f.DiffDetail.Grep(regexp)
+	for i := range matches {
+		matches[i].Filename = f.Filename
+	}
+	return matches
+}
+
+// DiffMatch is an added or removed line matching a regexp
+type DiffMatch struct {
+	// Commit is the long hash of the commit, set by GrepDiff
+	Commit   string
+	Filename string
+	Hunk     Hunk
+	// Side is LineAdded or LineRemoved
+	Side LineKind
+	// LineNo is the line number in the new file for an added line, in the original file for a removed line
+	LineNo int
+	Line   Line
+}
+
 type Hunk struct {
 	Header       string
 	Content      string
 	RemovedLines []string
 	AddedLines   []string
+	// OldStart and OldLines are the range of the hunk in the original file, from the "@@ -OldStart,OldLines +NewStart,NewLines @@" header
+	OldStart int
+	OldLines int
+	// NewStart and NewLines are the range of the hunk in the new file
+	NewStart int
+	NewLines int
+	// Section is the text following the header, usually the enclosing function
+	Section string
+	// Lines are the lines of the hunk, in order
+	Lines []Line
+}
+
+// LineKind is the kind of a line in a hunk
+type LineKind string
+
+const (
+	LineContext LineKind = "context"
+	LineAdded   LineKind = "added"
+	LineRemoved LineKind = "removed"
+)
+
+// Line is a line of a hunk. OldLineNo is 0 for an added line and NewLineNo is 0 for a removed line.
+type Line struct {
+	Kind      LineKind
+	Content   string
+	OldLineNo int
+	NewLineNo int
+	// NoNewlineAtEOF is set when the line is the last one of the file and has no trailing newline
+	NoNewlineAtEOF bool
 }
 
 // CloneOpts is a optional structs for git clone command
@@ -0,0 +1,208 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	semver "github.com/Masterminds/semver/v3"
+)
+
+// Bump is a semantic version increment
+type Bump int
+
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b Bump) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// DefaultBumpRules are the increments of the conventional commit types used by NextVersion. Breaking changes always bump the major version.
+var DefaultBumpRules = map[string]Bump{
+	"feat": BumpMinor,
+	"fix":  BumpPatch,
+	"perf": BumpPatch,
+}
+
+// NextVersionOpts is a optional struct for NextVersion
+type NextVersionOpts struct {
+	// Rev is the revision to release, HEAD if empty
+	Rev string
+	// Match are the glob patterns of the release tags, as in DescribeOpt. Default to v[0-9]*
+	Match []string
+	// BumpRules maps the conventional commit types to their increment. Default to DefaultBumpRules
+	BumpRules map[string]Bump
+	// DefaultBump is the increment of the commits which are not conventional or whose type is not in BumpRules
+	DefaultBump Bump
+	// Prerelease is the prerelease identifier (ie. "rc"). If set, the next version is a numbered prerelease (ie. 1.2.0-rc.1, then 1.2.0-rc.2)
+	Prerelease string
+	// InitialVersion is used when no tag matches. Default to v0.0.0
+	InitialVersion string
+}
+
+// NextVersion is the next semantic version of a revision, computed from the commits since the previous tag
+type NextVersion struct {
+	PreviousTag string          `json:"previous_tag"`
+	Previous    *semver.Version `json:"previous"`
+	Version     *semver.Version `json:"version"`
+	// Tag is the version with the prefix of the previous tag (ie. v1.2.0)
+	Tag     string `json:"tag"`
+	Bump    Bump   `json:"bump"`
+	Commits int    `json:"commits"`
+}
+
+// NextVersion computes the next semantic version from the conventional commits since the latest matching tag
+func (r Repo) NextVersion(ctx context.Context, opts NextVersionOpts) (*NextVersion, error) {
+	if opts.Rev == "" {
+		opts.Rev = "HEAD"
+	}
+	if len(opts.Match) == 0 {
+		opts.Match = []string{"v[0-9]*"}
+	}
+	if opts.BumpRules == nil {
+		opts.BumpRules = DefaultBumpRules
+	}
+	if opts.InitialVersion == "" {
+		opts.InitialVersion = "v0.0.0"
+	}
+
+	var output = new(NextVersion)
+	var err error
+	output.PreviousTag, err = r.latestTag(ctx, opts.Rev, opts.Match)
+	if err != nil {
+		return nil, err
+	}
+	logOpts := LogOpts{
+		Revisions:    []string{opts.Rev},
+		NoMerges:     true,
+		CommitOption: CommitOption{DisableFiles: true},
+	}
+	if output.PreviousTag != "" {
+		output.Previous, err = semver.NewVersion(output.PreviousTag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semver tag %s: %w", output.PreviousTag, err)
+		}
+		logOpts.Revisions = []string{output.PreviousTag + ".." + opts.Rev}
+	} else {
+		output.Previous, err = semver.NewVersion(opts.InitialVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid initial version %s: %w", opts.InitialVersion, err)
+		}
+	}
+
+	it := r.LogIter(ctx, logOpts)
+	defer it.Close()
+	for it.Next() {
+		output.Commits++
+		if b := commitBump(it.Commit(), opts.BumpRules, opts.DefaultBump); b > output.Bump {
+			output.Bump = b
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	output.Version, err = bumpVersion(output.Previous, output.Bump, opts.Prerelease)
+	if err != nil {
+		return nil, err
+	}
+	output.Tag = output.Version.Original()
+	return output, nil
+}
+
+// latestTag returns the latest tag matching the patterns reachable from rev, or an empty string
+func (r Repo) latestTag(ctx context.Context, rev string, match []string) (string, error) {
+	args := []string{"describe", "--tags", "--abbrev=0"}
+	for _, m := range match {
+		args = append(args, "--match", m)
+	}
+	args = append(args, rev)
+	tag, err := r.runCmd(ctx, "git", args...)
+	if err != nil {
+		if IsGitErrorKind(err, UnknownError) && (strings.Contains(err.Error(), "No names found") || strings.Contains(err.Error(), "No tags can describe")) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(tag), nil
+}
+
+func commitBump(c Commit, rules map[string]Bump, defaultBump Bump) Bump {
+	cc, err := c.Conventional()
+	if err != nil {
+		return defaultBump
+	}
+	if cc.Breaking {
+		return BumpMajor
+	}
+	if b, has := rules[cc.Type]; has {
+		return b
+	}
+	return defaultBump
+}
+
+// bumpVersion increments the version. The core of a prerelease version (ie. 1.2.0 for 1.2.0-rc.1) is considered
+// as unreleased: it is only incremented if the bump is greater than the one which produced it.
+func bumpVersion(v *semver.Version, b Bump, prerelease string) (*semver.Version, error) {
+	next := *v
+	if v.Prerelease() != "" {
+		next, _ = next.SetPrerelease("")
+		switch {
+		case b == BumpMajor && (next.Minor() != 0 || next.Patch() != 0):
+			next = next.IncMajor()
+		case b == BumpMinor && next.Patch() != 0:
+			next = next.IncMinor()
+		}
+	} else {
+		switch b {
+		case BumpMajor:
+			next = next.IncMajor()
+		case BumpMinor:
+			next = next.IncMinor()
+		case BumpPatch:
+			next = next.IncPatch()
+		}
+	}
+	next, _ = next.SetMetadata("")
+
+	if prerelease == "" {
+		if b == BumpNone && v.Prerelease() == "" {
+			return v, nil
+		}
+		return &next, nil
+	}
+
+	// 1.2.0-rc.1 is followed by 1.2.0-rc.2 when the core is unchanged
+	n := 1
+	if v.Prerelease() != "" && next.Major() == v.Major() && next.Minor() == v.Minor() && next.Patch() == v.Patch() {
+		if i, err := strconv.Atoi(strings.TrimPrefix(v.Prerelease(), prerelease+".")); err == nil && strings.HasPrefix(v.Prerelease(), prerelease+".") {
+			n = i + 1
+		}
+	} else if b == BumpNone {
+		next = next.IncPatch()
+	}
+	next, err := next.SetPrerelease(prerelease + "." + strconv.Itoa(n))
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bumpVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		bump       Bump
+		prerelease string
+		want       string
+	}{
+		{"v1.2.3", BumpNone, "", "v1.2.3"},
+		{"v1.2.3", BumpPatch, "", "v1.2.4"},
+		{"v1.2.3", BumpMinor, "", "v1.3.0"},
+		{"v1.2.3", BumpMajor, "", "v2.0.0"},
+		{"1.2.3+build.1", BumpPatch, "", "1.2.4"},
+		{"v1.3.0-rc.1", BumpPatch, "", "v1.3.0"},
+		{"v1.3.0-rc.1", BumpMinor, "", "v1.3.0"},
+		{"v1.3.0-rc.1", BumpMajor, "", "v2.0.0"},
+		{"v2.0.0-rc.1", BumpMajor, "", "v2.0.0"},
+		{"v1.2.3", BumpMinor, "rc", "v1.3.0-rc.1"},
+		{"v1.2.3", BumpNone, "rc", "v1.2.4-rc.1"},
+		{"v1.3.0-rc.1", BumpPatch, "rc", "v1.3.0-rc.2"},
+		{"v1.3.0-rc.9", BumpNone, "rc", "v1.3.0-rc.10"},
+		{"v1.3.0-beta.2", BumpPatch, "rc", "v1.3.0-rc.1"},
+		{"v1.3.0-rc.2", BumpMajor, "rc", "v2.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.bump.String()+" "+tt.prerelease, func(t *testing.T) {
+			v, err := semver.NewVersion(tt.version)
+			require.NoError(t, err)
+			got, err := bumpVersion(v, tt.bump, tt.prerelease)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Original())
+		})
+	}
+}
+
+func Test_commitBump(t *testing.T) {
+	tests := []struct {
+		subject string
+		body    string
+		want    Bump
+	}{
+		{"feat: add NextVersion", "", BumpMinor},
+		{"fix(log): handle empty commits", "", BumpPatch},
+		{"docs: update README", "", BumpNone},
+		{"Update README", "", BumpNone},
+		{"refactor!: drop the Commits helper", "", BumpMajor},
+		{"fix: handle empty commits", "BREAKING CHANGE: the files are not computed anymore", BumpMajor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			assert.Equal(t, tt.want, commitBump(Commit{Subject: tt.subject, Body: tt.body}, DefaultBumpRules, BumpNone))
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	r, err := New(context.TODO(), ".")
+	require.NoError(t, err)
+
+	v, err := r.NextVersion(context.TODO(), NextVersionOpts{DefaultBump: BumpPatch})
+	require.NoError(t, err)
+	require.NotNil(t, v.Version)
+	t.Logf("%s -> %s (%s, %d commits)", v.Previous, v.Tag, v.Bump, v.Commits)
+	if v.Commits > 0 {
+		assert.True(t, v.Version.GreaterThan(v.Previous))
+	}
+}